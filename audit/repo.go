@@ -0,0 +1,334 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/duke605/NickFury/datastore"
+	"github.com/duke605/NickFury/route"
+)
+
+// Entry is a record of a change made to the routes or map of a channel
+type Entry struct {
+	ID        uint64        `json:"id"`
+	Time      time.Time     `json:"time"`
+	Action    string        `json:"action"`
+	ActorID   string        `json:"actor_id"`
+	TargetIDs []string      `json:"target_ids,omitempty"`
+	ChannelID string        `json:"channel_id"`
+	GuildID   string        `json:"guild_id,omitempty"`
+	MessageID string        `json:"message_id,omitempty"`
+	Before    []route.Route `json:"before,omitempty"`
+	After     []route.Route `json:"after,omitempty"`
+	MapBefore *route.Map    `json:"map_before,omitempty"`
+	MapAfter  *route.Map    `json:"map_after,omitempty"`
+	Reverts   []uint64      `json:"reverts,omitempty"`
+}
+
+// ErrConflict is returned when an entry can not be reverted because what it changed has
+// been changed again
+var ErrConflict = errors.New("audit: changed since entry was recorded")
+
+// Snapshot is the state of a channel's routes and map at a point in time
+type Snapshot struct {
+	Routes []route.Route
+	Map    *route.Map
+}
+
+// Diff fills in the entry with the routes and map that are different between the snapshots.
+// Before holds the routes that were removed or changed as they were and After holds the routes
+// that were added or changed as they are now. Returns false if nothing changed
+func (e *Entry) Diff(before, after Snapshot) bool {
+	old := map[string]route.Route{}
+	for _, r := range before.Routes {
+		old[string(r.GetID())] = r
+	}
+
+	targets := map[string]struct{}{}
+	for _, r := range after.Routes {
+		id := string(r.GetID())
+		prev, ok := old[id]
+		delete(old, id)
+		if ok && same(prev, r) {
+			continue
+		}
+
+		if ok {
+			e.Before = append(e.Before, prev)
+			targets[prev.UserID] = struct{}{}
+		}
+		e.After = append(e.After, r)
+		targets[r.UserID] = struct{}{}
+	}
+
+	// Routes that are left were removed
+	for _, r := range before.Routes {
+		if _, ok := old[string(r.GetID())]; ok {
+			e.Before = append(e.Before, r)
+			targets[r.UserID] = struct{}{}
+		}
+	}
+
+	if !same(before.Map, after.Map) {
+		e.MapBefore = before.Map
+		e.MapAfter = after.Map
+	}
+
+	for id := range targets {
+		e.TargetIDs = append(e.TargetIDs, id)
+	}
+	sort.Strings(e.TargetIDs)
+
+	return len(e.Before) > 0 || len(e.After) > 0 || e.MapBefore != nil || e.MapAfter != nil
+}
+
+// Revert returns the snapshot with the changes in the entry undone. The snapshot must have
+// the routes and map the entry changed as they were left by the entry or ErrConflict is returned
+func (e Entry) Revert(s Snapshot) (Snapshot, error) {
+	routes := map[string]route.Route{}
+	for _, r := range s.Routes {
+		routes[string(r.GetID())] = r
+	}
+
+	// Checking that nothing the entry changed has been changed again
+	after := map[string]struct{}{}
+	for _, r := range e.After {
+		id := string(r.GetID())
+		after[id] = struct{}{}
+		if cur, ok := routes[id]; !ok || !same(cur, r) {
+			return s, ErrConflict
+		}
+	}
+	for _, r := range e.Before {
+		if _, ok := after[string(r.GetID())]; ok {
+			continue
+		}
+		if _, ok := routes[string(r.GetID())]; ok {
+			return s, ErrConflict
+		}
+	}
+	mapChanged := e.MapBefore != nil || e.MapAfter != nil
+	if mapChanged && !same(s.Map, e.MapAfter) {
+		return s, ErrConflict
+	}
+
+	// Putting back the routes and map as they were before the entry
+	for id := range after {
+		delete(routes, id)
+	}
+	for _, r := range e.Before {
+		routes[string(r.GetID())] = r
+	}
+
+	reverted := Snapshot{Map: s.Map}
+	if mapChanged {
+		reverted.Map = e.MapBefore
+	}
+	for _, r := range routes {
+		reverted.Routes = append(reverted.Routes, r)
+	}
+	sort.Slice(reverted.Routes, func(i, j int) bool {
+		return string(reverted.Routes[i].GetID()) < string(reverted.Routes[j].GetID())
+	})
+
+	return reverted, nil
+}
+
+// same returns true if the values are stored the same way
+func same(a, b interface{}) bool {
+	bufA, errA := json.Marshal(a)
+	bufB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(bufA, bufB)
+}
+
+// Repository handles the communication between the application and
+// persistant storage
+type Repository struct {
+	*datastore.Datastore
+}
+
+// NewRepo creates a new Repository
+func NewRepo(db *bolt.DB) *Repository {
+	return &Repository{
+		Datastore: &datastore.Datastore{DB: db},
+	}
+}
+
+// Append adds the entry to the end of the log and sets its ID
+func (repo *Repository) Append(ctx context.Context, e *Entry) error {
+	return repo.InTransaction(ctx, true, func(_ context.Context, tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("audit"))
+		if err != nil {
+			return err
+		}
+
+		e.ID, err = b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		buf, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		return b.Put(entryKey(e.ID), buf)
+	})
+}
+
+// GetEntries returns the latest entries for the channel with the newest first. Only entries
+// that changed the user's routes are returned when userID is not empty. At most limit entries
+// are returned
+func (repo *Repository) GetEntries(ctx context.Context, channelID, userID string, limit int) ([]Entry, error) {
+	entries := []Entry{}
+	err := repo.InTransaction(ctx, false, func(_ context.Context, tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("audit"))
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+		for k, v := c.Last(); k != nil && len(entries) < limit; k, v = c.Prev() {
+			e := Entry{}
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+
+			if e.ChannelID == channelID && (userID == "" || e.HasTarget(userID)) {
+				entries = append(entries, e)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetEntriesSince returns the entries for every channel that were recorded at or after the
+// time with the newest first
+func (repo *Repository) GetEntriesSince(ctx context.Context, since time.Time) ([]Entry, error) {
+	entries := []Entry{}
+	err := repo.InTransaction(ctx, false, func(_ context.Context, tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("audit"))
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			e := Entry{}
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if e.Time.Before(since) {
+				break
+			}
+
+			entries = append(entries, e)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetUndoable returns up to n of the latest entries for the channel that have not been
+// reverted with the newest first. Entries that revert other entries are left out
+func (repo *Repository) GetUndoable(ctx context.Context, channelID string, n int) ([]Entry, error) {
+	entries := []Entry{}
+	err := repo.InTransaction(ctx, false, func(_ context.Context, tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("audit"))
+		if b == nil {
+			return nil
+		}
+
+		// Entries that revert others always come after the entries they revert
+		reverted := map[uint64]struct{}{}
+		c := b.Cursor()
+		for k, v := c.Last(); k != nil && len(entries) < n; k, v = c.Prev() {
+			e := Entry{}
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if e.ChannelID != channelID {
+				continue
+			}
+
+			for _, id := range e.Reverts {
+				reverted[id] = struct{}{}
+			}
+			if _, ok := reverted[e.ID]; ok || len(e.Reverts) > 0 {
+				continue
+			}
+
+			entries = append(entries, e)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// DeleteBefore removes the entries that were recorded before the time
+func (repo *Repository) DeleteBefore(ctx context.Context, t time.Time) error {
+	return repo.InTransaction(ctx, true, func(_ context.Context, tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("audit"))
+		if b == nil {
+			return nil
+		}
+
+		// Entries are stored in the order they were recorded so the oldest are first
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.First() {
+			e := Entry{}
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			if !e.Time.Before(t) {
+				return nil
+			}
+
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// HasTarget returns true if the entry changed the user's routes
+func (e Entry) HasTarget(userID string) bool {
+	for _, id := range e.TargetIDs {
+		if id == userID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// entryKey creates the key for an entry so entries are sorted by the order they were added
+func entryKey(id uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, id)
+	return b
+}
@@ -0,0 +1,298 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/route"
+	"github.com/spf13/viper"
+)
+
+// defaultAlertBefore is how long before a raid ends officers are alerted about uncovered
+// paths when no time has been configured
+const defaultAlertBefore = time.Hour
+
+type alerts struct {
+	Show    alertsShow    `cmd:"" default:"1" help:"Shows the alert settings for the channel"`
+	Channel alertsChannel `cmd:"" help:"Sets the officer channel alerts for this channel are posted to"`
+	Ends    alertsEnds    `cmd:"" help:"Sets when the raid in this channel ends. Routes are archived and cleared when it ends"`
+	Off     alertsOff     `cmd:"" help:"Stops alerts for this channel"`
+}
+
+type alertsShow struct{}
+
+func (alertsShow) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, m route.Map) error {
+	sess.ChannelMessageSendEmbed(msg.ChannelID, alertSettingsEmbed(m))
+	return nil
+}
+
+type alertsChannel struct {
+	Channel ChannelMention `arg:"" name:"channel" help:"The channel to post alerts to"`
+	Before  time.Duration  `name:"before" default:"1h" help:"How long before the raid ends to alert about uncovered paths (eg. 30m)"`
+}
+
+func (alertsChannel) restricted() bool { return true }
+
+func (alertsChannel) audited() bool { return true }
+
+func (a alertsChannel) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service) error {
+	cmdPrefix := viper.GetString("COMMAND_PREFIX")
+	footer := fmt.Sprintf("Type %salerts channel --help for command usage", cmdPrefix)
+
+	// Only allowing alerts to be posted to channels in the same server
+	if channelGuildID(sess, string(a.Channel)) != msg.GuildID {
+		return UsageError{
+			Param:    "channel",
+			Message:  "Must be a channel in this server",
+			Provided: a.Channel,
+			Footer:   footer,
+		}
+	}
+	if a.Before <= 0 {
+		return UsageError{
+			Param:    "before",
+			Message:  "Must be longer than 0 (eg. 30m)",
+			Provided: a.Before,
+			Footer:   footer,
+		}
+	}
+
+	return updateAlertSettings(sess, msg, rs, func(m *route.Map) {
+		m.AlertChannelID = string(a.Channel)
+		m.AlertBefore = a.Before
+	})
+}
+
+type alertsEnds struct {
+	Until string `arg:"" name:"until" help:"When the raid ends as a time (eg. 20:00) or how long from now (eg. 3h). Use off to clear it"`
+}
+
+func (alertsEnds) restricted() bool { return true }
+
+func (alertsEnds) audited() bool { return true }
+
+func (a alertsEnds) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service) error {
+	ends := time.Time{}
+	if a.Until != "off" {
+		var err error
+		ends, err = parseExpiry(a.Until, "until", "alerts ends", messageTime(msg))
+		if err != nil {
+			return err
+		}
+	}
+
+	return updateAlertSettings(sess, msg, rs, func(m *route.Map) {
+		m.EndsAt = ends
+	})
+}
+
+type alertsOff struct{}
+
+func (alertsOff) restricted() bool { return true }
+
+func (alertsOff) audited() bool { return true }
+
+func (alertsOff) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service) error {
+	return updateAlertSettings(sess, msg, rs, func(m *route.Map) {
+		m.AlertChannelID = ""
+		m.AlertBefore = 0
+	})
+}
+
+// updateAlertSettings changes the alert settings of the channel's map and shows the result
+func updateAlertSettings(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, update func(m *route.Map)) error {
+	var m route.Map
+	err := rs.InTransaction(context.Background(), true, func(ctx context.Context, _ *bolt.Tx) error {
+		var err error
+		m, err = getChannelMap(ctx, rs, msg.ChannelID)
+		if err != nil {
+			return err
+		}
+
+		update(&m)
+		err = rs.InsertMap(ctx, m)
+		if err != nil {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong when saving the map",
+				Stack:   debug.Stack(),
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sess.ChannelMessageSendEmbed(msg.ChannelID, alertSettingsEmbed(m))
+	return nil
+}
+
+// alertSettingsEmbed describes the alert settings of the map
+func alertSettingsEmbed(m route.Map) *discordgo.MessageEmbed {
+	info := newInfoEmbed()
+	info.Title = "Alerts"
+	if m.AlertChannelID == "" {
+		info.Description = "Alerts are off for this channel"
+		if !m.EndsAt.IsZero() {
+			info.Description += fmt.Sprintf("\nThe raid ends <t:%d:R>", m.EndsAt.Unix())
+		}
+
+		return info
+	}
+
+	info.Description = fmt.Sprintf("Alerts are posted to <#%s> when a path loses its only member", m.AlertChannelID)
+	if m.EndsAt.IsZero() {
+		info.Description += "\nSet when the raid ends to be alerted about uncovered paths before it ends"
+	} else {
+		info.Description += fmt.Sprintf("\nUncovered paths are alerted %s before the raid ends <t:%d:R>", formatAge(alertBefore(m)), m.EndsAt.Unix())
+	}
+
+	return info
+}
+
+// alertBefore returns how long before the raid ends officers are alerted about uncovered paths
+func alertBefore(m route.Map) time.Duration {
+	if m.AlertBefore <= 0 {
+		return defaultAlertBefore
+	}
+
+	return m.AlertBefore
+}
+
+// alertUncovered alerts officers about the paths that no longer have anyone on them. Paths
+// officers have already been alerted about are not announced again until they are covered
+func alertUncovered(sess *discordgo.Session, rs *route.Service, channelID string, refs []RouteRef, reason string) {
+	var m route.Map
+	gaps := []RouteRef{}
+
+	err := rs.InTransaction(context.Background(), true, func(ctx context.Context, _ *bolt.Tx) error {
+		var err error
+		m, err = rs.GetMapForChannel(ctx, channelID)
+		if err == sql.ErrNoRows || (err == nil && m.AlertChannelID == "") {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		routes, err := rs.GetRoutesInChannel(ctx, channelID)
+		if err != nil {
+			return err
+		}
+		idx := route.IndexRoutes(routes)
+
+		state, err := rs.GetAlertState(ctx, channelID)
+		if err != nil {
+			return err
+		}
+		state.Paths = stillUncovered(state.Paths, idx)
+
+		for _, ref := range refs {
+			key := route.RouteKey(ref.Section, ref.Path)
+			primary, _, _ := route.SplitRoutes(idx[key])
+			if len(primary) > 0 || state.HasPath(key) {
+				continue
+			}
+
+			gaps = append(gaps, ref)
+			state.Paths = append(state.Paths, key)
+		}
+
+		return rs.SetAlertState(ctx, state)
+	})
+	if err != nil {
+		fmt.Println("Error occured checking for uncovered paths: ", err)
+		return
+	}
+	if len(gaps) == 0 {
+		return
+	}
+
+	sess.ChannelMessageSend(m.AlertChannelID, fmt.Sprintf("⚠️ %s in <#%s> no longer has anyone on it %s", joinRouteRefs(gaps), channelID, reason))
+}
+
+// SendDeadlineAlerts alerts officers about the paths that nobody is on when the raids they are
+// in are about to end. Officers are only alerted once for each time a raid ends
+func SendDeadlineAlerts(sess *discordgo.Session, rs *route.Service, now time.Time) {
+	ctx := context.Background()
+	maps, err := rs.GetMaps(ctx)
+	if err != nil {
+		fmt.Println("Error occured getting maps for alerts: ", err)
+		return
+	}
+
+	for _, m := range maps {
+		if m.AlertChannelID == "" || m.EndsAt.IsZero() || !now.Before(m.EndsAt) || now.Before(m.EndsAt.Add(-alertBefore(m))) {
+			continue
+		}
+
+		gaps := []RouteRef{}
+		err = rs.InTransaction(ctx, true, func(ctx context.Context, _ *bolt.Tx) error {
+			state, err := rs.GetAlertState(ctx, m.ID)
+			if err != nil || state.Deadline.Equal(m.EndsAt) {
+				return err
+			}
+
+			routes, err := rs.GetRoutesInChannel(ctx, m.ID)
+			if err != nil {
+				return err
+			}
+			idx := route.IndexRoutes(routes)
+
+			// Remembering every gap so they are not announced again when members unlink
+			state.Paths = []string{}
+			for section := 1; section <= int(m.Sections); section++ {
+				for _, p := range m.Paths(section) {
+					primary, _, _ := route.SplitRoutes(idx[route.RouteKey(section, p)])
+					if len(primary) == 0 {
+						gaps = append(gaps, RouteRef{Section: section, Path: p})
+						state.Paths = append(state.Paths, route.RouteKey(section, p))
+					}
+				}
+			}
+			state.Deadline = m.EndsAt
+
+			return rs.SetAlertState(ctx, state)
+		})
+		if err != nil {
+			fmt.Println("Error occured checking for uncovered paths: ", err)
+			continue
+		}
+		if len(gaps) == 0 {
+			continue
+		}
+
+		sess.ChannelMessageSend(m.AlertChannelID, fmt.Sprintf(
+			"⏰ The raid in <#%s> ends <t:%d:R> and **%d** path(s) have nobody on them: %s",
+			m.ID, m.EndsAt.Unix(), len(gaps), truncate(joinRouteRefs(gaps), maxMessageLength-200),
+		))
+	}
+}
+
+// stillUncovered returns the keys of the paths that still have nobody on them
+func stillUncovered(keys []string, idx map[string][]route.Route) []string {
+	uncovered := []string{}
+	for _, key := range keys {
+		primary, _, _ := route.SplitRoutes(idx[key])
+		if len(primary) == 0 {
+			uncovered = append(uncovered, key)
+		}
+	}
+
+	return uncovered
+}
+
+// unlinkReason describes who unlinked from a path for an alert
+func unlinkReason(userID, actorID string) string {
+	if userID == actorID {
+		return fmt.Sprintf("after <@!%s> unlinked", userID)
+	}
+
+	return fmt.Sprintf("after <@!%s> was unlinked by <@!%s>", userID, actorID)
+}
@@ -0,0 +1,215 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/audit"
+	"github.com/duke605/NickFury/route"
+)
+
+// Reactions officers can use on a link request to approve or deny it
+const (
+	approveEmoji = "✅"
+	denyEmoji    = "❌"
+)
+
+type approval struct {
+	State string `arg:"" name:"state" enum:"on,off" help:"Whether links made by members need to be approved by an officer (on or off)"`
+}
+
+func (approval) restricted() bool { return true }
+
+func (approval) audited() bool { return true }
+
+func (a approval) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service) error {
+	err := rs.InTransaction(context.Background(), true, func(ctx context.Context, _ *bolt.Tx) error {
+		m, err := getChannelMap(ctx, rs, msg.ChannelID)
+		if err != nil {
+			return err
+		}
+
+		m.Approval = a.State == "on"
+		err = rs.InsertMap(ctx, m)
+		if err != nil {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong when saving the map",
+				Stack:   debug.Stack(),
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	info := newInfoEmbed()
+	info.Description = "Members can now link to routes in this channel without approval"
+	if a.State == "on" {
+		info.Description = fmt.Sprintf("Links made by members in this channel now need to be approved by an officer with %s or %s", approveEmoji, denyEmoji)
+	}
+	sess.ChannelMessageSendEmbed(msg.ChannelID, info)
+	return nil
+}
+
+type approve struct {
+	Request string `arg:"" name:"request" help:"The ID of the link request to approve"`
+}
+
+func (approve) restricted() bool { return true }
+
+func (approve) audited() bool { return true }
+
+func (a approve) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, bu *BoardUpdater) error {
+	note, err := resolveRequest(sess, rs, msg.ChannelID, a.Request, msg.Author.ID, true, "", messageTime(msg))
+	if err != nil {
+		return err
+	}
+
+	bu.Request(msg.ChannelID, note)
+	return nil
+}
+
+type deny struct {
+	Request string   `arg:"" name:"request" help:"The ID of the link request to deny"`
+	Reason  []string `arg:"" optional:"" name:"reason" help:"Why the request was denied"`
+}
+
+func (deny) restricted() bool { return true }
+
+func (deny) audited() bool { return true }
+
+func (d deny) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, bu *BoardUpdater) error {
+	note, err := resolveRequest(sess, rs, msg.ChannelID, d.Request, msg.Author.ID, false, strings.Join(d.Reason, " "), messageTime(msg))
+	if err != nil {
+		return err
+	}
+
+	bu.Request(msg.ChannelID, note)
+	return nil
+}
+
+// requestApproval adds the reactions officers can use to approve or deny the link request
+func requestApproval(sess *discordgo.Session, msg *discordgo.MessageCreate) {
+	sess.MessageReactionAdd(msg.ChannelID, msg.ID, approveEmoji)
+	sess.MessageReactionAdd(msg.ChannelID, msg.ID, denyEmoji)
+}
+
+// resolveRequest approves or denies the pending link request and lets the requester know.
+// A note for the route board is returned
+func resolveRequest(sess *discordgo.Session, rs *route.Service, channelID, requestID, officerID string, approved bool, reason string, now time.Time) (string, error) {
+	var requested []route.Route
+
+	err := rs.InTransaction(context.Background(), true, func(ctx context.Context, _ *bolt.Tx) error {
+		routes, err := getChannelRoutes(ctx, rs, channelID)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range routes {
+			if r.Pending && r.RequestID == requestID {
+				requested = append(requested, r)
+			}
+		}
+		if len(requested) == 0 {
+			return Warning{
+				Message: fmt.Sprintf("There is no pending link request with the ID `%s` in this channel", requestID),
+			}
+		}
+
+		for _, r := range requested {
+			if approved {
+				r.Pending = false
+				r.UpdatedAt = now
+				err = rs.InsertRoute(ctx, r)
+			} else {
+				err = rs.DeleteRoute(ctx, r)
+			}
+			if err != nil {
+				return SystemError{
+					error:   err,
+					Message: "Something went wrong resolving the link request",
+					Stack:   debug.Stack(),
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	refs := make([]RouteRef, len(requested))
+	for i, r := range requested {
+		refs[i] = RouteRef{Section: r.Section, Path: r.Path}
+	}
+
+	outcome := "denied"
+	if approved {
+		outcome = "approved"
+	}
+
+	// Letting the requester know what happened to their request
+	notice := fmt.Sprintf("<@!%s> your request for %s was %s by <@!%s>", requested[0].UserID, joinRouteRefs(refs), outcome, officerID)
+	if reason != "" {
+		notice += fmt.Sprintf(": %s", reason)
+	}
+	sess.ChannelMessageSend(channelID, notice)
+
+	return fmt.Sprintf("%s the request of <@!%s> for %s", strings.Title(outcome), requested[0].UserID, joinRouteRefs(refs)), nil
+}
+
+// ResolveRequestReaction approves or denies a link request when an officer reacts to it
+func ResolveRequestReaction(sess *discordgo.Session, rs *route.Service, ar *audit.Repository, bu *BoardUpdater, evt *discordgo.MessageReactionAdd) {
+	if evt.Emoji.Name != approveEmoji && evt.Emoji.Name != denyEmoji {
+		return
+	}
+
+	officer, err := isUserOrRole(sess, evt.GuildID, evt.UserID, trustedUsers, trustedRoles)
+	if err != nil || !officer {
+		return
+	}
+
+	before, err := takeSnapshot(context.Background(), rs, evt.ChannelID)
+	if err != nil {
+		fmt.Println("Error occured taking a snapshot for the audit log: ", err)
+		return
+	}
+
+	now := time.Now()
+	approved := evt.Emoji.Name == approveEmoji
+	note, err := resolveRequest(sess, rs, evt.ChannelID, evt.MessageID, evt.UserID, approved, "", now)
+	if err != nil {
+
+		// Reactions on messages that are not pending requests are ignored
+		if !errors.As(err, new(Warning)) {
+			fmt.Println("Error occured resolving link request: ", err)
+		}
+
+		return
+	}
+
+	action := "deny"
+	if approved {
+		action = "approve"
+	}
+	recordChange(ar, rs, audit.Entry{
+		Time:      now,
+		Action:    action,
+		ActorID:   evt.UserID,
+		ChannelID: evt.ChannelID,
+		GuildID:   evt.GuildID,
+		MessageID: evt.MessageID,
+	}, before)
+
+	bu.Request(evt.ChannelID, note)
+}
@@ -0,0 +1,298 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/audit"
+	"github.com/duke605/NickFury/route"
+	"github.com/spf13/viper"
+)
+
+// maxArchiveList is the most archives that are listed at once
+const maxArchiveList = 25
+
+type archive struct {
+	List archiveList `cmd:"" default:"1" help:"Lists the archived raids for the channel"`
+	Show archiveShow `cmd:"" help:"Shows the route board of an archived raid"`
+	Diff archiveDiff `cmd:"" help:"Compares the routes of two archived raids"`
+}
+
+type archiveList struct{}
+
+func (archiveList) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service) error {
+	archives, err := rs.GetArchives(context.Background(), msg.ChannelID, maxArchiveList)
+	if err != nil {
+		return SystemError{
+			error:   err,
+			Message: "Something went wrong getting the archives for this channel",
+			Stack:   debug.Stack(),
+		}
+	}
+	if len(archives) == 0 {
+		return Warning{
+			Message: "There are no archived raids for this channel",
+		}
+	}
+
+	lines := make([]string, len(archives))
+	for i, a := range archives {
+		lines[i] = fmt.Sprintf("`#%d` <t:%d:f> **%s** with %d route(s)", a.ID, a.Time.Unix(), a.Reason, len(a.Routes))
+		if a.ActorID != "" {
+			lines[i] += fmt.Sprintf(" by <@!%s>", a.ActorID)
+		}
+	}
+
+	info := newInfoEmbed()
+	info.Title = "Archived raids"
+	info.Description = truncate(strings.Join(lines, "\n"), 2048)
+	sess.ChannelMessageSendEmbed(msg.ChannelID, info)
+	return nil
+}
+
+type archiveShow struct {
+	ID uint64 `arg:"" name:"id" help:"The number of the archive to show"`
+}
+
+func (s archiveShow) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, mc *MemberCache) error {
+	a, err := getArchive(rs, msg.ChannelID, s.ID)
+	if err != nil {
+		return err
+	}
+
+	content := fmt.Sprintf("**Archive #%d** from <t:%d:f>", a.ID, a.Time.Unix())
+	for _, p := range composeBoardPages(sess, rs, mc, a.Map, msg.GuildID, content, a.Routes) {
+
+		// Renaming the embeds so they are not cleaned up with old route boards
+		if p.embed != nil {
+			p.embed.Author.Name = fmt.Sprintf("Routes (Archive #%d)", a.ID)
+		}
+
+		_, err = sess.ChannelMessageSendComplex(msg.ChannelID, &discordgo.MessageSend{
+			Content: p.content,
+			Embed:   p.embed,
+		})
+		if err != nil {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong sending the archived route board",
+				Stack:   debug.Stack(),
+			}
+		}
+	}
+
+	return nil
+}
+
+type archiveDiff struct {
+	From uint64 `arg:"" name:"from" help:"The number of the archive to compare from"`
+	To   uint64 `arg:"" optional:"" name:"to" help:"The number of the archive to compare to. Leave out to compare to the current routes"`
+}
+
+func (d archiveDiff) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service) error {
+	from, err := getArchive(rs, msg.ChannelID, d.From)
+	if err != nil {
+		return err
+	}
+
+	// Comparing to the current routes when no second archive was given
+	to := route.Archive{Time: time.Now()}
+	toName := "now"
+	if d.To != 0 {
+		to, err = getArchive(rs, msg.ChannelID, d.To)
+		if err != nil {
+			return err
+		}
+		toName = fmt.Sprintf("#%d", d.To)
+	} else {
+		to.Routes, err = getChannelRoutes(context.Background(), rs, msg.ChannelID)
+		if err != nil {
+			return err
+		}
+	}
+
+	lines := diffRoutes(from.Routes, to.Routes)
+	if len(lines) == 0 {
+		lines = []string{"The same users were linked to every route"}
+	}
+
+	info := newInfoEmbed()
+	info.Title = fmt.Sprintf("Changes from #%d to %s", d.From, toName)
+	info.Description = truncate(strings.Join(lines, "\n"), 2048)
+	sess.ChannelMessageSendEmbed(msg.ChannelID, info)
+	return nil
+}
+
+// getArchive gets the archive for the channel and transforms any errors into errors that
+// can be shown to the user
+func getArchive(rs *route.Service, channelID string, id uint64) (route.Archive, error) {
+	a, err := rs.GetArchive(context.Background(), channelID, id)
+	if err == sql.ErrNoRows {
+		return a, Warning{
+			Message: fmt.Sprintf("There is no archive `#%d` for this channel", id),
+		}
+	} else if err != nil {
+		return a, SystemError{
+			error:   err,
+			Message: "Something went wrong getting the archive",
+			Stack:   debug.Stack(),
+		}
+	}
+
+	return a, nil
+}
+
+// diffRoutes creates a line for every route that has different users linked to it
+func diffRoutes(from, to []route.Route) []string {
+	users := func(routes []route.Route) map[RouteRef]string {
+		idx := map[RouteRef][]string{}
+		for _, r := range routes {
+			ref := RouteRef{Section: r.Section, Path: r.Path}
+			idx[ref] = append(idx[ref], fmt.Sprintf("<@!%s>", r.UserID))
+		}
+
+		joined := map[RouteRef]string{}
+		for ref, mentions := range idx {
+			sort.Strings(mentions)
+			joined[ref] = strings.Join(mentions, "/")
+		}
+
+		return joined
+	}
+	before, after := users(from), users(to)
+
+	refs := []RouteRef{}
+	for ref := range before {
+		refs = append(refs, ref)
+	}
+	for ref := range after {
+		if _, ok := before[ref]; !ok {
+			refs = append(refs, ref)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Section != refs[j].Section {
+			return refs[i].Section < refs[j].Section
+		}
+
+		return refs[i].Path < refs[j].Path
+	})
+
+	lines := []string{}
+	for _, ref := range refs {
+		if before[ref] == after[ref] {
+			continue
+		}
+
+		b, a := before[ref], after[ref]
+		if b == "" {
+			b = "*nobody*"
+		}
+		if a == "" {
+			a = "*nobody*"
+		}
+		lines = append(lines, fmt.Sprintf("**%s** %s → %s", ref, b, a))
+	}
+
+	return lines
+}
+
+// archiveRoutes saves the channel's map and routes as an archive. Nothing is archived when
+// the channel does not have a map or routes
+func archiveRoutes(ctx context.Context, rs *route.Service, guildID, channelID, reason, actorID string, now time.Time) error {
+	m, err := rs.GetMapForChannel(ctx, channelID)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	routes, err := rs.GetRoutesInChannel(ctx, channelID)
+	if err != nil || len(routes) == 0 {
+		return err
+	}
+
+	return rs.InsertArchive(ctx, &route.Archive{
+		ChannelID: channelID,
+		GuildID:   guildID,
+		Time:      now,
+		Reason:    reason,
+		ActorID:   actorID,
+		Map:       m,
+		Routes:    routes,
+	})
+}
+
+// EndRaids archives and clears the routes of raids that have reached the time they end. Each
+// channel is locked while its raid is ended so the changes are not mixed up with a command
+// running in it
+func EndRaids(sess *discordgo.Session, rs *route.Service, ar *audit.Repository, bu *BoardUpdater, km *KeyedMutex, now time.Time) {
+	maps, err := rs.GetMaps(context.Background())
+	if err != nil {
+		fmt.Println("Error occured getting maps for ending raids: ", err)
+		return
+	}
+
+	for _, m := range maps {
+		if !m.EndsAt.IsZero() && !now.Before(m.EndsAt) {
+			endRaid(sess, rs, ar, bu, km, m.ID, now)
+		}
+	}
+}
+
+// endRaid archives the routes of the raid in the channel and clears them along with when the
+// raid ends so the same raid is not archived again by a later purge or map
+func endRaid(sess *discordgo.Session, rs *route.Service, ar *audit.Repository, bu *BoardUpdater, km *KeyedMutex, channelID string, now time.Time) {
+	unlock := km.Lock(channelID)
+	defer unlock()
+
+	ctx := context.Background()
+	before, err := takeSnapshot(ctx, rs, channelID)
+	if err != nil {
+		fmt.Println("Error occured taking a snapshot for the audit log: ", err)
+		return
+	}
+
+	guildID := channelGuildID(sess, channelID)
+	ended := false
+	err = rs.InTransaction(ctx, true, func(ctx context.Context, _ *bolt.Tx) error {
+
+		// Checking the raid again since a command may have changed when it ends before the
+		// channel was locked
+		m, err := rs.GetMapForChannel(ctx, channelID)
+		if err != nil || m.EndsAt.IsZero() || now.Before(m.EndsAt) {
+			return err
+		}
+
+		err = archiveRoutes(ctx, rs, guildID, channelID, "end", "", now)
+		if err != nil {
+			return err
+		}
+
+		err = rs.DeleteAllRoutesForChannel(ctx, channelID)
+		if err != nil {
+			return err
+		}
+
+		m.EndsAt = time.Time{}
+		ended = true
+		return rs.InsertMap(ctx, m)
+	})
+	if err != nil {
+		fmt.Println("Error occured ending raid: ", err)
+		return
+	}
+	if !ended {
+		return
+	}
+
+	recordChange(ar, rs, audit.Entry{Time: now, Action: "end", ChannelID: channelID, GuildID: guildID}, before)
+	bu.Request(channelID, fmt.Sprintf("The raid has ended and its routes were archived and cleared. Use `%sarchive` to look back on them", viper.GetString("COMMAND_PREFIX")))
+}
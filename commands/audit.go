@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/duke605/NickFury/audit"
+	"github.com/duke605/NickFury/route"
+)
+
+// maxEntryChanges is the most changes shown for a single entry in the history
+const maxEntryChanges = 5
+
+// takeSnapshot gets the current routes and map for the channel
+func takeSnapshot(ctx context.Context, rs *route.Service, channelID string) (audit.Snapshot, error) {
+	s := audit.Snapshot{}
+	err := rs.InTransaction(ctx, false, func(ctx context.Context, _ *bolt.Tx) error {
+		var err error
+		s.Routes, err = rs.GetRoutesInChannel(ctx, channelID)
+		if err != nil {
+			return err
+		}
+
+		m, err := rs.GetMapForChannel(ctx, channelID)
+		if err == nil {
+			s.Map = &m
+		}
+
+		return nil
+	})
+
+	return s, err
+}
+
+// recordChange adds the entry to the audit log when the channel's routes or map are different
+// from the snapshot
+func recordChange(ar *audit.Repository, rs *route.Service, e audit.Entry, before audit.Snapshot) {
+	ctx := context.Background()
+	after, err := takeSnapshot(ctx, rs, e.ChannelID)
+	if err != nil {
+		fmt.Println("Error occured taking a snapshot for the audit log: ", err)
+		return
+	}
+
+	if !e.Diff(before, after) {
+		return
+	}
+
+	if err = ar.Append(ctx, &e); err != nil {
+		fmt.Println("Error occured adding to the audit log: ", err)
+	}
+}
+
+// describeEntry creates a short summary of the changes in the entry
+func describeEntry(e audit.Entry) string {
+	changes := []string{}
+
+	// Describing changes to the map
+	switch {
+	case e.MapBefore == nil && e.MapAfter != nil:
+		changes = append(changes, fmt.Sprintf("map created with %d section(s)", e.MapAfter.Sections))
+	case e.MapBefore != nil && e.MapAfter != nil && (e.MapBefore.Sections != e.MapAfter.Sections || strings.Join(e.MapBefore.MaxPaths, "") != strings.Join(e.MapAfter.MaxPaths, "")):
+		changes = append(changes, fmt.Sprintf("map changed to %d section(s)", e.MapAfter.Sections))
+	case e.MapBefore != nil || e.MapAfter != nil:
+		changes = append(changes, "map settings changed")
+	}
+
+	before := map[string]route.Route{}
+	for _, r := range e.Before {
+		before[string(r.GetID())] = r
+	}
+
+	// Describing routes that were added or changed
+	for _, r := range e.After {
+		ref := RouteRef{Section: r.Section, Path: r.Path}
+		prev, ok := before[string(r.GetID())]
+		delete(before, string(r.GetID()))
+
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("+%s <@!%s>", ref, r.UserID))
+		case prev.Section != r.Section || prev.Path != r.Path || prev.UserID != r.UserID:
+			changes = append(changes, fmt.Sprintf("%s <@!%s> → %s <@!%s>", RouteRef{Section: prev.Section, Path: prev.Path}, prev.UserID, ref, r.UserID))
+		default:
+			changes = append(changes, fmt.Sprintf("~%s <@!%s>", ref, r.UserID))
+		}
+	}
+
+	// Describing routes that were removed
+	for _, r := range e.Before {
+		if _, ok := before[string(r.GetID())]; ok {
+			changes = append(changes, fmt.Sprintf("-%s <@!%s>", RouteRef{Section: r.Section, Path: r.Path}, r.UserID))
+		}
+	}
+
+	if n := len(changes); n > maxEntryChanges {
+		changes = append(changes[:maxEntryChanges], fmt.Sprintf("and %d more", n-maxEntryChanges))
+	}
+
+	return strings.Join(changes, ", ")
+}
@@ -0,0 +1,220 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/route"
+	"github.com/spf13/viper"
+)
+
+const (
+	// maxBoardNotes is the most notes that will be shown with a board
+	maxBoardNotes = 10
+
+	// maxMessageLength is the most characters discord allows in a message
+	maxMessageLength = 2000
+)
+
+// RenderRouteBoard creates a BoardRenderer that renders the latest routes for the channel
+func RenderRouteBoard(sess *discordgo.Session, rs *route.Service, mc *MemberCache) BoardRenderer {
+	return func(channelID string, notes []string, repost bool) {
+		ctx := context.Background()
+		m, err := rs.GetMapForChannel(ctx, channelID)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				fmt.Println("Error occured getting map for route board: ", err)
+			}
+
+			return
+		}
+
+		routes, err := rs.GetRoutesInChannel(ctx, channelID)
+		if err != nil {
+			fmt.Println("Error occured getting routes for route board: ", err)
+			return
+		}
+
+		// Only showing the latest notes so the message stays a reasonable size
+		if n := len(notes); n > maxBoardNotes {
+			notes = append(notes[n-maxBoardNotes:], fmt.Sprintf("*...and %d earlier change(s)*", n-maxBoardNotes))
+		}
+
+		pages := composeBoardPages(sess, rs, mc, m, channelGuildID(sess, channelID), strings.Join(notes, "\n"), routes)
+		if repost {
+			err = postRouteBoard(sess, rs, channelID, pages)
+		} else {
+			err = sendRouteBoard(sess, rs, channelID, pages)
+		}
+		if err != nil {
+			fmt.Println("Error occured sending route board: ", err)
+		}
+	}
+}
+
+// boardPage is a single message of a route board
+type boardPage struct {
+	content string
+	embed   *discordgo.MessageEmbed
+}
+
+// sendRouteBoard shows the pages of the route board in the channel. The channel's existing
+// board is edited in place and a new board is only posted when the channel does not have one
+// or it can no longer be edited
+func sendRouteBoard(sess *discordgo.Session, rs *route.Service, channelID string, pages []boardPage) error {
+	ids, err := rs.GetBoardMessageIDs(context.Background(), channelID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	// Posting a new board when there isn't one or the board needs a different number of messages
+	if len(ids) != len(pages) {
+		return postRouteBoard(sess, rs, channelID, pages)
+	}
+
+	for i, id := range ids {
+		edit := discordgo.NewMessageEdit(channelID, id).SetContent(pages[i].content)
+		if pages[i].embed != nil {
+			edit.SetEmbed(pages[i].embed)
+		}
+
+		_, err = sess.ChannelMessageEditComplex(edit)
+		if err == nil {
+			continue
+		}
+
+		// Posting a new board when the old one was deleted
+		if isUnknownMessage(err) {
+			return postRouteBoard(sess, rs, channelID, pages)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// postRouteBoard posts the pages as a new route board for the channel and removes the
+// previous one
+func postRouteBoard(sess *discordgo.Session, rs *route.Service, channelID string, pages []boardPage) error {
+	ctx := context.Background()
+	oldIDs, _ := rs.GetBoardMessageIDs(ctx, channelID)
+
+	// Sending the board with one page per message
+	ids := make([]string, len(pages))
+	for i, p := range pages {
+		newMsg, err := sess.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+			Content: p.content,
+			Embed:   p.embed,
+		})
+		if err != nil {
+			for _, id := range ids[:i] {
+				sess.ChannelMessageDelete(channelID, id)
+			}
+
+			return err
+		}
+		ids[i] = newMsg.ID
+	}
+
+	err := rs.SetBoardMessageIDs(ctx, channelID, ids)
+	if err != nil {
+		return err
+	}
+
+	if viper.GetBool("PIN_ROUTE_BOARD") {
+		sess.ChannelMessagePin(channelID, ids[0])
+	}
+
+	// Removing the previous board and any older boards from before boards were edited in place
+	for _, id := range oldIDs {
+		sess.ChannelMessageDelete(channelID, id)
+	}
+	cleanupPreviousRouteEmbeds(sess, channelID, ids[0])
+
+	return nil
+}
+
+// composeBoardPages creates the pages of the route board in the format configured for the
+// map. The content is shown at the top of the board when it is not empty
+func composeBoardPages(sess *discordgo.Session, rs *route.Service, mc *MemberCache, m route.Map, guildID, content string, routes []route.Route) []boardPage {
+	content = truncate(content, maxMessageLength)
+	idx := route.IndexRoutes(routes)
+	pages := []boardPage{}
+
+	if m.Format == route.FormatText {
+		tables := rs.ComposeTable(m, idx, displayNames(sess, mc, guildID, plainNameMode(m.NameMode), routes))
+
+		// Giving the content its own message when it can not fit with the first table
+		if content != "" && utf8.RuneCountInString(content)+1+utf8.RuneCountInString(tables[0]) > maxMessageLength {
+			pages = append(pages, boardPage{content: content})
+			content = ""
+		}
+
+		for i, t := range tables {
+			if i == 0 && content != "" {
+				t = content + "\n" + t
+			}
+
+			pages = append(pages, boardPage{content: t})
+		}
+
+		return pages
+	}
+
+	for i, embed := range rs.ComposeEmbeds(m, idx, displayNames(sess, mc, guildID, m.NameMode, routes)) {
+		p := boardPage{embed: embed}
+		if i == 0 {
+			p.content = content
+		}
+
+		pages = append(pages, p)
+	}
+
+	return pages
+}
+
+// channelGuildID returns the ID of the guild the channel is in or an empty string if the
+// channel could not be found
+func channelGuildID(sess *discordgo.Session, channelID string) string {
+	ch, err := sess.State.Channel(channelID)
+	if err != nil {
+		ch, err = sess.Channel(channelID)
+	}
+	if err != nil {
+		return ""
+	}
+
+	return ch.GuildID
+}
+
+// truncate shortens the text to be no longer than limit characters
+func truncate(text string, limit int) string {
+	r := []rune(text)
+	if len(r) <= limit {
+		return text
+	}
+
+	return string(r[:limit-1]) + "…"
+}
+
+// isUnknownMessage returns true if the error is from discord saying the message does not exist
+func isUnknownMessage(err error) bool {
+	var re *discordgo.RESTError
+	return errors.As(err, &re) && re.Message != nil && re.Message.Code == discordgo.ErrCodeUnknownMessage
+}
+
+// guildChannels returns the channels in the guild
+func guildChannels(sess *discordgo.Session, guildID string) ([]*discordgo.Channel, error) {
+	g, err := sess.State.Guild(guildID)
+	if err == nil && len(g.Channels) > 0 {
+		return g.Channels, nil
+	}
+
+	return sess.GuildChannels(guildID)
+}
@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/route"
+	"github.com/spf13/viper"
+)
+
+// checkFinding is a kind of problem found with the routes of a channel
+type checkFinding struct {
+	title string
+	items []string
+}
+
+type check struct{}
+
+func (check) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, mc *MemberCache, m route.Map) error {
+	routes, err := getChannelRoutes(context.Background(), rs, msg.ChannelID)
+	if err != nil {
+		return err
+	}
+
+	// Finding linked users that are no longer in the guild
+	left := map[string]bool{}
+	for _, r := range routes {
+		if _, ok := left[r.UserID]; ok {
+			continue
+		}
+
+		_, gone, err := mc.Lookup(sess, msg.GuildID, r.UserID)
+		left[r.UserID] = err == nil && gone
+	}
+
+	idx := route.IndexRoutes(routes)
+	c := route.ComputeCoverage(m, idx)
+	findings := checkRoutes(m, idx, left, memberPathLimit(m))
+
+	info := newInfoEmbed()
+	info.Title = "Route check"
+	info.Description = fmt.Sprintf("**%d%%** filled · **%d%%** complete · **%d** unassigned path(s)", c.FillPercent(), c.CompletePercent(), c.Unassigned)
+	if len(findings) == 0 {
+		info.Description += "\nNo problems found"
+	}
+	for _, f := range findings {
+		info.Fields = append(info.Fields, &discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("%s (%d)", f.title, len(f.items)),
+			Value: truncate(strings.Join(f.items, "\n"), 1024),
+		})
+	}
+
+	sess.ChannelMessageSendEmbed(msg.ChannelID, info)
+	return nil
+}
+
+// checkRoutes finds the problems with the routes on the map ordered by priority. left holds
+// the users that are no longer in the guild and limit is the most paths a member should be on
+func checkRoutes(m route.Map, idx map[string][]route.Route, left map[string]bool, limit int) []checkFinding {
+	uncovered := checkFinding{title: "Uncovered paths"}
+	oversubscribed := checkFinding{title: "Oversubscribed paths"}
+	overLimit := checkFinding{title: "Members over the limit"}
+	gone := checkFinding{title: "Members no longer in the server"}
+	short := checkFinding{title: "Paths that need more members"}
+
+	capacity := m.PathCapacity()
+	onPaths := map[string][]RouteRef{}
+	goneFrom := map[string][]RouteRef{}
+	for section := 1; section <= int(m.Sections); section++ {
+		for _, p := range m.Paths(section) {
+			ref := RouteRef{Section: section, Path: p}
+			routes := idx[route.RouteKey(section, p)]
+			primary, _, _ := route.SplitRoutes(routes)
+
+			switch n := len(primary); {
+			case n == 0:
+				uncovered.items = append(uncovered.items, fmt.Sprintf("**%s**", ref))
+			case n > capacity:
+				oversubscribed.items = append(oversubscribed.items, fmt.Sprintf("**%s** %d/%d linked", ref, n, capacity))
+			case n < capacity:
+				short.items = append(short.items, fmt.Sprintf("**%s** %d/%d linked", ref, n, capacity))
+			}
+
+			for _, r := range primary {
+				onPaths[r.UserID] = append(onPaths[r.UserID], ref)
+			}
+			for _, r := range routes {
+				if left[r.UserID] {
+					goneFrom[r.UserID] = append(goneFrom[r.UserID], ref)
+				}
+			}
+		}
+	}
+
+	// Finding members that are on more paths than allowed or more than one path in a section
+	for _, userID := range sortedKeys(onPaths) {
+		refs := onPaths[userID]
+		sections := map[int]int{}
+		doubled := false
+		for _, ref := range refs {
+			sections[ref.Section]++
+			doubled = doubled || sections[ref.Section] > 1
+		}
+
+		if len(refs) > limit || doubled {
+			overLimit.items = append(overLimit.items, fmt.Sprintf("<@!%s> on %d path(s): %s", userID, len(refs), joinRouteRefs(refs)))
+		}
+	}
+
+	for _, userID := range sortedKeys(goneFrom) {
+		gone.items = append(gone.items, fmt.Sprintf("<@!%s> on %s", userID, joinRouteRefs(goneFrom[userID])))
+	}
+
+	// Listing the problems that leave paths without anyone first
+	findings := []checkFinding{}
+	for _, f := range []checkFinding{uncovered, oversubscribed, overLimit, gone, short} {
+		if len(f.items) > 0 {
+			findings = append(findings, f)
+		}
+	}
+
+	return findings
+}
+
+// memberPathLimit returns the most paths a member should be linked to on the map. Members
+// are allowed one path per section unless a limit is configured
+func memberPathLimit(m route.Map) int {
+	if limit := viper.GetInt("MAX_MEMBER_PATHS"); limit > 0 {
+		return limit
+	}
+
+	return int(m.Sections)
+}
+
+// sortedKeys returns the keys of the map in order
+func sortedKeys(m map[string][]RouteRef) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
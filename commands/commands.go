@@ -1,10 +1,17 @@
 package commands
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"runtime/debug"
+	"time"
+	"unicode/utf8"
 
+	"github.com/alecthomas/kong"
 	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/route"
+	"github.com/spf13/viper"
 )
 
 var trustedUsers = map[string]struct{}{
@@ -20,13 +27,40 @@ var trustedRoles = map[string]struct{}{
 
 // Root ...
 type Root struct {
-	Link   Link   `cmd:"" help:"Links yourself to a section and path"`
-	Unlink unlink `cmd:"" help:"Unlinks yourself from a path and/or section"`
-	Show   show   `cmd:"" help:"Shows all assigned and unassigned routes for the channel"`
-	Map    _map   `cmd:"" help:"Configures the map for the channel"`
-	Purge  Purge  `cmd:"" help:"Clears all linked routes for the channel"`
-	Ping   Ping   `cmd:"" help:"Diagnostics command"`
-	About  About  `cmd:"" help:"Shows information about this bot"`
+	Link        Link        `cmd:"" help:"Links yourself to a section and path"`
+	Unlink      unlink      `cmd:"" help:"Unlinks yourself from a path and/or section"`
+	Show        show        `cmd:"" help:"Shows all assigned and unassigned routes for the channel"`
+	Map         _map        `cmd:"" help:"Configures the map for the channel"`
+	Purge       Purge       `cmd:"" help:"Clears all linked routes for the channel"`
+	Lock        lock        `cmd:"" help:"Locks the routes for the channel so only officers can change them"`
+	Unlock      unlock      `cmd:"" help:"Unlocks the routes for the channel"`
+	Move        move        `cmd:"" help:"Moves yourself from one route to another"`
+	Swap        swap        `cmd:"" help:"Swaps the routes of two users"`
+	Format      format      `cmd:"" help:"Sets the format the route board is shown in"`
+	Names       names       `cmd:"" help:"Sets how linked users are named on the route board"`
+	Stale       stale       `cmd:"" help:"Lists links that have not changed in a while"`
+	Note        note        `cmd:"" help:"Sets the note shown with your link on the route board"`
+	Reserve     reserve     `cmd:"" help:"Reserves a route for a user until they link to it"`
+	Approval    approval    `cmd:"" help:"Sets whether links made by members need to be approved by an officer"`
+	Approve     approve     `cmd:"" help:"Approves a link request"`
+	Deny        deny        `cmd:"" help:"Denies a link request"`
+	History     history     `cmd:"" help:"Shows who changed the routes for the channel"`
+	Undo        undo        `cmd:"" help:"Undoes the latest changes to the routes for the channel"`
+	Archive     archive     `cmd:"" help:"Shows the routes of past raids"`
+	Mine        mine        `cmd:"" help:"Shows the routes you are linked to in every channel"`
+	Alerts      alerts      `cmd:"" help:"Sets up alerts to officers about paths with nobody on them"`
+	Check       check       `cmd:"" help:"Checks the routes for the channel for problems"`
+	Overview    overview    `cmd:"" help:"Shows how full the maps in every channel are"`
+	Stats       stats       `cmd:"" help:"Shows how often a user has taken part in raids"`
+	Leaderboard leaderboard `cmd:"" help:"Ranks members by how often they have taken part in raids"`
+	Ping        Ping        `cmd:"" help:"Diagnostics command"`
+	About       About       `cmd:"" help:"Shows information about this bot"`
+}
+
+// AfterApply binds a provider for the channel's map so every command and argument that
+// needs the map shares a single lookup
+func (Root) AfterApply(inv *Invocation, k *kong.Kong) error {
+	return kong.BindToProvider(inv.Map).Apply(k)
 }
 
 // cleanupPreviousRouteEmbeds deletes messages from the bot that are route embeds that come
@@ -99,6 +133,27 @@ func isUserOrRole(sess *discordgo.Session, guildID, userID string, authUserIDs,
 	return hasRole(mem, authRoles), nil
 }
 
+// checkOfficer returns a PermissionError if the author of the message is not a trusted
+// user and does not have a trusted role
+func checkOfficer(sess *discordgo.Session, m *discordgo.MessageCreate) error {
+	auth, err := isUserOrRole(sess, m.GuildID, m.Author.ID, trustedUsers, trustedRoles)
+	if err != nil {
+		return SystemError{
+			error:   err,
+			Message: "Something went wrong getting your role information",
+			Stack:   debug.Stack(),
+		}
+	}
+
+	if !auth {
+		return PermissionError{
+			Message: "You do not have permission to use this command",
+		}
+	}
+
+	return nil
+}
+
 // newInfoEmbed creates a new info embed with some field prefilled
 func newInfoEmbed() *discordgo.MessageEmbed {
 	return &discordgo.MessageEmbed{
@@ -109,3 +164,158 @@ func newInfoEmbed() *discordgo.MessageEmbed {
 		},
 	}
 }
+
+// getChannelMap gets the map for the channel and transforms any errors into errors
+// that can be shown to the user
+func getChannelMap(ctx context.Context, rs *route.Service, channelID string) (route.Map, error) {
+	cmdPrefix := viper.GetString("COMMAND_PREFIX")
+
+	m, err := rs.GetMapForChannel(ctx, channelID)
+	if err != nil && err != sql.ErrNoRows {
+		return m, SystemError{
+			error:   err,
+			Message: "Something went wrong getting the map for this channel",
+			Stack:   debug.Stack(),
+		}
+	} else if err == sql.ErrNoRows {
+		return m, Warning{
+			Message: fmt.Sprintf("There is no map configured for this channel. Use the `%smap` command to configure one", cmdPrefix),
+		}
+	}
+
+	return m, nil
+}
+
+// getChannelRoutes gets the routes linked in the channel and transforms any errors into
+// errors that can be shown to the user
+func getChannelRoutes(ctx context.Context, rs *route.Service, channelID string) ([]route.Route, error) {
+	routes, err := rs.GetRoutesInChannel(ctx, channelID)
+	if err != nil {
+		return nil, SystemError{
+			error:   err,
+			Message: "Something went wrong getting linked routes for channel",
+			Stack:   debug.Stack(),
+		}
+	}
+
+	return routes, nil
+}
+
+// validateRoute checks that the section and path exist on the map. param is the name of
+// the argument the route was provided with and cmd is the name of the command being run
+func validateRoute(m route.Map, section int, path, param, cmd string) error {
+	cmdPrefix := viper.GetString("COMMAND_PREFIX")
+	footer := fmt.Sprintf("Type %s%s --help for command usage", cmdPrefix, cmd)
+
+	// Checking if the section is in the acceptable range
+	if section < 1 || section > int(m.Sections) {
+		return UsageError{
+			Param:    param,
+			Message:  fmt.Sprintf("Section must be between 1 and %d (inclusive)", m.Sections),
+			Provided: section,
+			Footer:   footer,
+		}
+	}
+
+	// Checking that the path is a valid path for the map
+	if len(path) != 1 || !m.IsValidPath(section, path) {
+		paths := m.Paths(section)
+		return UsageError{
+			Param:    param,
+			Message:  fmt.Sprintf("Path must be between A and %s (inclusive)", paths[len(paths)-1]),
+			Provided: path,
+			Footer:   footer,
+		}
+	}
+
+	return nil
+}
+
+// displayNames returns the name each user is shown as on a board for the name mode. Users
+// that are shown as mentions or can not be found are left out and users that have left the
+// guild are flagged
+func displayNames(sess *discordgo.Session, mc *MemberCache, guildID, mode string, routes []route.Route) map[string]string {
+	names := map[string]string{}
+	for _, r := range routes {
+		if _, ok := names[r.UserID]; ok {
+			continue
+		}
+
+		mem, left, err := mc.Lookup(sess, guildID, r.UserID)
+		if err != nil {
+			continue
+		}
+
+		// Mentions of users that left can not be shown so they are named instead
+		if left {
+			names[r.UserID] = mem.User.Username + " (left)"
+			continue
+		}
+
+		switch mode {
+		case route.NameUsername:
+			names[r.UserID] = mem.User.Username
+		case route.NameNickname:
+			names[r.UserID] = mem.User.Username
+			if mem.Nick != "" {
+				names[r.UserID] = mem.Nick
+			}
+		}
+	}
+
+	return names
+}
+
+// plainNameMode returns the name mode to use for boards that can not show mentions
+func plainNameMode(mode string) string {
+	if mode == route.NameUsername {
+		return mode
+	}
+
+	return route.NameNickname
+}
+
+// messageTime returns when the message was sent or the current time if the message's
+// timestamp can not be read
+func messageTime(msg *discordgo.MessageCreate) time.Time {
+	t, err := msg.Timestamp.Parse()
+	if err != nil {
+		return time.Now()
+	}
+
+	return t
+}
+
+// formatAge formats the duration as days, hours and minutes (eg. 2d 4h)
+func formatAge(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+// sanitizeNote makes the note safe to show on a board and checks that it is not too long.
+// param is the name of the argument the note was provided with and cmd is the name of the
+// command being run
+func sanitizeNote(note, param, cmd string) (string, error) {
+	cmdPrefix := viper.GetString("COMMAND_PREFIX")
+
+	note = route.SanitizeNote(note)
+	if n := utf8.RuneCountInString(note); n > route.MaxNoteLength {
+		return "", UsageError{
+			Param:   param,
+			Message: fmt.Sprintf("Note must be %d characters or less (have %d)", route.MaxNoteLength, n),
+			Footer:  fmt.Sprintf("Type %s%s --help for command usage", cmdPrefix, cmd),
+		}
+	}
+
+	return note, nil
+}
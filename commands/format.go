@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/boltdb/bolt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/route"
+)
+
+type format struct {
+	Format string `arg:"" name:"format" enum:"embed,text" help:"The format the route board is shown in (embed or text)"`
+}
+
+func (format) restricted() bool { return true }
+
+func (format) audited() bool { return true }
+
+func (f format) Run(msg *discordgo.MessageCreate, rs *route.Service, bu *BoardUpdater) error {
+	err := rs.InTransaction(context.Background(), true, func(ctx context.Context, _ *bolt.Tx) error {
+		m, err := getChannelMap(ctx, rs, msg.ChannelID)
+		if err != nil {
+			return err
+		}
+
+		m.Format = f.Format
+		err = rs.InsertMap(ctx, m)
+		if err != nil {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong when saving the map",
+				Stack:   debug.Stack(),
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Posting a new board since the old board is in the old format
+	bu.Repost(msg.ChannelID, fmt.Sprintf("Route board is now shown as %s", f.Format))
+	return nil
+}
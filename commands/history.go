@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/audit"
+	"github.com/spf13/viper"
+)
+
+// maxHistoryLimit is the most entries the history command can show at once
+const maxHistoryLimit = 25
+
+type history struct {
+	User  Mention `name:"user" help:"Only shows changes to the user's routes"`
+	Limit int     `name:"limit" default:"10" help:"The number of changes to show"`
+}
+
+func (h *history) AfterApply() error {
+	cmdPrefix := viper.GetString("COMMAND_PREFIX")
+
+	// Checking that the limit is in the acceptable range
+	if h.Limit < 1 || h.Limit > maxHistoryLimit {
+		return UsageError{
+			Param:    "limit",
+			Message:  fmt.Sprintf("Must be between 1 and %d (inclusive)", maxHistoryLimit),
+			Provided: h.Limit,
+			Footer:   fmt.Sprintf("Type %shistory --help for command usage", cmdPrefix),
+		}
+	}
+
+	return nil
+}
+
+func (h *history) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, ar *audit.Repository) error {
+	entries, err := ar.GetEntries(context.Background(), msg.ChannelID, string(h.User), h.Limit)
+	if err != nil {
+		return SystemError{
+			error:   err,
+			Message: "Something went wrong getting the history for this channel",
+			Stack:   debug.Stack(),
+		}
+	}
+	if len(entries) == 0 {
+		return Warning{
+			Message: "There are no recorded changes to show",
+		}
+	}
+
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		actor := "*system*"
+		if e.ActorID != "" {
+			actor = fmt.Sprintf("<@!%s>", e.ActorID)
+		}
+
+		lines[i] = fmt.Sprintf("`#%d` <t:%d:R> %s **%s**: %s", e.ID, e.Time.Unix(), actor, e.Action, describeEntry(e))
+	}
+
+	info := newInfoEmbed()
+	info.Title = "Route history"
+	info.Description = truncate(strings.Join(lines, "\n"), 2048)
+	sess.ChannelMessageSendEmbed(msg.ChannelID, info)
+	return nil
+}
@@ -2,162 +2,152 @@ package commands
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"runtime/debug"
 	"strings"
+	"time"
 
-	"github.com/alecthomas/kong"
 	bolt "github.com/boltdb/bolt"
 	"github.com/bwmarrin/discordgo"
 	"github.com/duke605/NickFury/route"
-	"github.com/spf13/viper"
 )
 
 // Link ...
 type Link struct {
-	Section int    `arg:"" name:"section" help:"The section to link yourself to"`
-	Path    string `arg:"" name:"path" help:"The path to link yourself to"`
+	Routes RouteSelectors `arg:"" name:"routes" help:"The routes to link yourself to (eg. 1A 2C, 1A-1C or 2*)"`
 
-	User Mention `name:"user" help:"Sets the user that will be linked"`
+	User    Mention `name:"user" help:"Sets the user that will be linked"`
+	Note    string  `name:"note" help:"A short note shown with the link on the route board"`
+	Standby bool    `name:"standby" help:"Links as a backup that takes over the route when someone leaves it"`
 }
 
-// AfterApply ...
-func (l Link) AfterApply(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, k *kong.Kong) error {
-	cmdPrefix := viper.GetString("COMMAND_PREFIX")
-	l.Path = strings.ToUpper(l.Path)
+// restricted only allows officers to change the routes of other users
+func (l *Link) restricted() bool { return l.User != "" }
 
-	// Checking if the user is allowed to use the command
-	err := l.checkPermissions(sess, msg)
-	if err != nil {
-		return err
-	}
-
-	// Checking if a map exists for the channel
-	m, err := rs.GetMapForChannel(context.Background(), msg.ChannelID)
-	if err != nil && err != sql.ErrNoRows {
-		return SystemError{
-			error:   err,
-			Message: "Something went wrong getting the map for this channel",
-			Stack:   debug.Stack(),
-		}
-	} else if err == sql.ErrNoRows {
-		return Warning{
-			Message: fmt.Sprintf("There is no map configured for this channel. Use the `%smap` command to configure one", cmdPrefix),
-		}
-	}
-	kong.Bind(m).Apply(k)
-
-	// Checking if the section is in the acceptable range
-	if l.Section < 1 || l.Section > int(m.Sections) {
-		return UsageError{
-			Param:    "section",
-			Message:  fmt.Sprintf("Must be between 1 and %d (inclusive)", m.Sections),
-			Provided: l.Path,
-			Footer:   fmt.Sprintf("Type %slink --help for command usage", cmdPrefix),
-		}
-	}
-
-	// Checking that the path param is a valid character
-	if len(l.Path) > 1 {
-		return UsageError{
-			Param:    "path",
-			Message:  "Must be a single character",
-			Provided: l.Path,
-			Footer:   fmt.Sprintf("Type %slink --help for command usage", cmdPrefix),
-		}
-	}
+// lockable stops members from changing their own routes while the channel is locked
+func (l *Link) lockable() bool { return l.User == "" }
 
-	// Checking that the path param is a valid path for the map
-	if !m.IsValidPath(l.Section, l.Path) {
-		paths := m.Paths(l.Section)
-		return UsageError{
-			Param:    "path",
-			Message:  fmt.Sprintf("Must be between A and %s (inclusive)", paths[len(paths)-1]),
-			Provided: l.Path,
-			Footer:   fmt.Sprintf("Type %slink --help for command usage", cmdPrefix),
-		}
-	}
-
-	return nil
-}
-
-func (l Link) checkPermissions(sess *discordgo.Session, m *discordgo.MessageCreate) error {
-	// If the command was not invoked with the user flag then the command isn't restricted
-	if l.User == "" {
-		return nil
-	}
-
-	auth, err := isUserOrRole(sess, m.GuildID, m.Author.ID, trustedUsers, trustedRoles)
-	if err != nil {
-		return SystemError{
-			error:   err,
-			Message: "Something went wrong getting your role information",
-			Stack:   debug.Stack(),
-		}
-	}
-
-	if !auth {
-		return PermissionError{
-			Message: "You do not have permission to use this command",
-		}
-	}
-
-	return nil
-}
+func (l *Link) audited() bool { return true }
 
 // Run ...
-func (l Link) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, m route.Map) error {
-	var newRoute route.Route
+func (l *Link) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, m route.Map, bu *BoardUpdater) error {
 	var routes []route.Route
 	var err error
+	linked := []RouteRef{}
+	requested := []RouteRef{}
+	conflicts := []RouteRef{}
+	blocked := []RouteRef{}
 
-	l.Path = strings.ToUpper(l.Path)
 	userID := msg.Author.ID
 	if l.User != "" {
 		userID = string(l.User)
 	}
 
+	note, err := sanitizeNote(l.Note, "note", "link")
+	if err != nil {
+		return err
+	}
+
+	// Links members make need to be approved by an officer when the channel requires it
+	pending := false
+	if m.Approval && l.User == "" {
+		officer, err := isUserOrRole(sess, msg.GuildID, msg.Author.ID, trustedUsers, trustedRoles)
+		if err != nil {
+			return err
+		}
+		pending = !officer
+	}
+
+	now := messageTime(msg)
 	ctx := context.Background()
 	err = rs.InTransaction(ctx, true, func(ctx context.Context, _ *bolt.Tx) error {
 
 		// Getting the already selected routes for the channel
-		routes, err = rs.GetRoutesInChannel(ctx, msg.ChannelID)
+		routes, err = getChannelRoutes(ctx, rs, msg.ChannelID)
 		if err != nil {
-			return SystemError{
-				error:   err,
-				Message: "Something when wrong getting linked routes for channel",
-				Stack:   debug.Stack(),
-			}
+			return err
 		}
 
-		// Checking if the user is already linked to the section
+		// Finding the routes the user is already linked to
+		existing := map[RouteRef]route.Route{}
 		for _, r := range routes {
-			if r.Section == l.Section && r.Path == l.Path && r.UserID == userID {
-				m := "You are already linked to path %s in section %d"
-				if userID != msg.Author.ID {
-					m = "User is already linked to path %s in section %d"
+			if r.UserID == userID {
+				existing[RouteRef{Section: r.Section, Path: r.Path}] = r
+			}
+		}
+		idx := route.IndexRoutes(routes)
+
+		for i, ref := range l.Routes.Refs(m) {
+			newRoute := route.Route{
+				ID:        msg.ID,
+				UserID:    userID,
+				ChannelID: msg.ChannelID,
+				Path:      ref.Path,
+				Section:   ref.Section,
+				CreatedAt: now,
+				UpdatedAt: now,
+				Note:      note,
+				Standby:   l.Standby,
+				Pending:   pending,
+				RequestID: msg.ID,
+			}
+			if i > 0 {
+				newRoute.ID = fmt.Sprintf("%s:%d", msg.ID, i)
+			}
+
+			if r, ok := existing[ref]; ok {
+				if !r.Reserved {
+					conflicts = append(conflicts, ref)
+					continue
+				}
+
+				// Claiming the route that was reserved for the user
+				newRoute = r
+				newRoute.Reserved = false
+				newRoute.ExpiresAt = time.Time{}
+				newRoute.UpdatedAt = now
+				if note != "" {
+					newRoute.Note = note
 				}
-				return Warning{
-					Message: fmt.Sprintf(m, r.Path, l.Section),
+			} else if !l.Standby && reservedForOthers(idx[route.RouteKey(ref.Section, ref.Path)], userID, m.PathCapacity(), now) {
+				blocked = append(blocked, ref)
+				continue
+			}
+
+			// Inserting route into db
+			err = rs.InsertRoute(ctx, newRoute)
+			if err != nil {
+				return SystemError{
+					error:   err,
+					Message: "Something went wrong linking you to the new route",
+					Stack:   debug.Stack(),
 				}
 			}
-		}
 
-		// Inserting section into db
-		newRoute = route.Route{
-			ID:        msg.ID,
-			UserID:    userID,
-			ChannelID: msg.ChannelID,
-			Path:      strings.ToUpper(l.Path),
-			Section:   l.Section,
+			routes = append(routes, newRoute)
+			if newRoute.Pending {
+				requested = append(requested, ref)
+			} else {
+				linked = append(linked, ref)
+			}
 		}
-		err = rs.InsertRoute(ctx, newRoute)
-		if err != nil {
-			return SystemError{
-				error:   err,
-				Message: "Something went wrong linking you to the new route",
-				Stack:   debug.Stack(),
+
+		// Nothing was linked
+		if len(linked) == 0 && len(requested) == 0 {
+			reasons := []string{}
+			if len(conflicts) > 0 {
+				m := "You are already linked to %s"
+				if userID != msg.Author.ID {
+					m = "User is already linked to %s"
+				}
+				reasons = append(reasons, fmt.Sprintf(m, joinRouteRefs(conflicts)))
+			}
+			if len(blocked) > 0 {
+				reasons = append(reasons, fmt.Sprintf("%s is reserved for someone else", joinRouteRefs(blocked)))
+			}
+
+			return Warning{
+				Message: strings.Join(reasons, "\n"),
 			}
 		}
 
@@ -167,19 +157,27 @@ func (l Link) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *rou
 		return err
 	}
 
-	// Creating a map for quick lookup of assigned routes
-	idx := map[string][]string{}
-	key := fmt.Sprintf("%d:%s", newRoute.Section, newRoute.Path)
-	idx[key] = append(idx[key], newRoute.UserID)
-	for _, r := range routes {
-		key = fmt.Sprintf("%d:%s", r.Section, r.Path)
-		idx[key] = append(idx[key], r.UserID)
+	suffix := ""
+	if l.Standby {
+		suffix = " as standby"
 	}
 
-	newMsg, err := sess.ChannelMessageSendEmbed(msg.ChannelID, rs.ComposeEmbed(m, idx))
-	if err == nil {
-		cleanupPreviousRouteEmbeds(sess, newMsg.ChannelID, newMsg.ID)
+	lines := []string{}
+	if len(linked) > 0 {
+		lines = append(lines, fmt.Sprintf("Linked <@!%s> to %s%s", userID, joinRouteRefs(linked), suffix))
+	}
+	if len(requested) > 0 {
+		lines = append(lines, fmt.Sprintf("<@!%s> requested %s%s (request `%s`)", userID, joinRouteRefs(requested), suffix, msg.ID))
+		requestApproval(sess, msg)
+	}
+	content := strings.Join(lines, "\n")
+	if len(conflicts) > 0 {
+		content += fmt.Sprintf("\nAlready linked to %s", joinRouteRefs(conflicts))
+	}
+	if len(blocked) > 0 {
+		content += fmt.Sprintf("\nReserved for someone else: %s", joinRouteRefs(blocked))
 	}
 
+	bu.Request(msg.ChannelID, content)
 	return nil
 }
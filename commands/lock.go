@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/boltdb/bolt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/route"
+)
+
+type lock struct{}
+
+func (lock) restricted() bool { return true }
+
+func (lock) audited() bool { return true }
+
+func (lock) Run(msg *discordgo.MessageCreate, rs *route.Service, bu *BoardUpdater) error {
+	return setMapLocked(msg, rs, bu, true)
+}
+
+type unlock struct{}
+
+func (unlock) restricted() bool { return true }
+
+func (unlock) audited() bool { return true }
+
+func (unlock) Run(msg *discordgo.MessageCreate, rs *route.Service, bu *BoardUpdater) error {
+	return setMapLocked(msg, rs, bu, false)
+}
+
+// setMapLocked sets the locked state of the channel's map and updates the board to show it
+func setMapLocked(msg *discordgo.MessageCreate, rs *route.Service, bu *BoardUpdater, locked bool) error {
+	state := "unlocked"
+	if locked {
+		state = "locked"
+	}
+
+	err := rs.InTransaction(context.Background(), true, func(ctx context.Context, _ *bolt.Tx) error {
+		m, err := getChannelMap(ctx, rs, msg.ChannelID)
+		if err != nil {
+			return err
+		}
+
+		// Checking if the map is already in the requested state
+		if m.Locked == locked {
+			return Warning{
+				Message: fmt.Sprintf("Routes for this channel are already %s", state),
+			}
+		}
+
+		m.Locked = locked
+		err = rs.InsertMap(ctx, m)
+		if err != nil {
+			return SystemError{
+				error:   err,
+				Message: fmt.Sprintf("Something went wrong when saving the %s map", state),
+				Stack:   debug.Stack(),
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	bu.Request(msg.ChannelID, fmt.Sprintf("Routes for this channel have been %s by <@!%s>", state, msg.Author.ID))
+	return nil
+}
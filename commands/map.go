@@ -2,6 +2,7 @@ package commands
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"runtime/debug"
 	"strings"
@@ -15,15 +16,13 @@ import (
 type _map struct {
 	Sections byte     `arg:"" name:"sections" help:"The number of sections the map has"`
 	Paths    []string `arg:"" name:"max_paths" help:"The max letter each section goes to. If sections was 4 then there should be 4 letters"`
+
+	Capacity int `name:"capacity" default:"1" help:"The number of users expected on each path"`
 }
 
 func (m *_map) AfterApply(sess *discordgo.Session, msg *discordgo.MessageCreate) error {
 	cmdPrefix := viper.GetString("COMMAND_PREFIX")
 
-	if err := m.checkPermissions(sess, msg); err != nil {
-		return err
-	}
-
 	// Checking that section is a number above 0
 	if m.Sections < 1 {
 		return UsageError{
@@ -33,6 +32,15 @@ func (m *_map) AfterApply(sess *discordgo.Session, msg *discordgo.MessageCreate)
 		}
 	}
 
+	// Checking that at least one user is expected on each path
+	if m.Capacity < 1 {
+		return UsageError{
+			Param:   "capacity",
+			Message: "Must be greater than 0",
+			Footer:  fmt.Sprintf("Type %smap --help for command usage", cmdPrefix),
+		}
+	}
+
 	// Checking if each path provided is only one char and is between A-Z
 	for i, p := range m.Paths {
 		p = strings.ToUpper(p)
@@ -69,30 +77,25 @@ func (m *_map) AfterApply(sess *discordgo.Session, msg *discordgo.MessageCreate)
 	return nil
 }
 
-func (_map) checkPermissions(sess *discordgo.Session, m *discordgo.MessageCreate) error {
-	auth, err := isUserOrRole(sess, m.GuildID, m.Author.ID, trustedUsers, trustedRoles)
-	if err != nil {
-		return SystemError{
-			error:   err,
-			Message: "Something went wrong getting your role information",
-			Stack:   debug.Stack(),
-		}
-	}
+func (_map) restricted() bool { return true }
 
-	if !auth {
-		return PermissionError{
-			Message: "You do not have permission to use this command",
-		}
-	}
+func (_map) audited() bool { return true }
 
-	return nil
-}
+func (m *_map) Run(msg *discordgo.MessageCreate, rs *route.Service, bu *BoardUpdater) error {
+	err := rs.InTransaction(context.Background(), true, func(ctx context.Context, tx *bolt.Tx) error {
 
-func (m *_map) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service) error {
-	return rs.InTransaction(context.Background(), true, func(ctx context.Context, tx *bolt.Tx) error {
+		// Keeping the routes for the previous map so they can be looked back on
+		err := archiveRoutes(ctx, rs, msg.GuildID, msg.ChannelID, "map", msg.Author.ID, messageTime(msg))
+		if err != nil {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong archiving the routes for the channel",
+				Stack:   debug.Stack(),
+			}
+		}
 
 		// Clearing all linked routes for the channel
-		err := rs.DeleteAllRoutesForChannel(ctx, msg.ChannelID)
+		err = rs.DeleteAllRoutesForChannel(ctx, msg.ChannelID)
 		if err != nil {
 			return SystemError{
 				error:   err,
@@ -101,11 +104,28 @@ func (m *_map) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *ro
 			}
 		}
 
+		// Keeping the settings from the previous map
+		prev, err := rs.GetMapForChannel(ctx, msg.ChannelID)
+		if err != nil && err != sql.ErrNoRows {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong getting the map for this channel",
+				Stack:   debug.Stack(),
+			}
+		}
+
 		// Inserting the map into the database
 		err = rs.InsertMap(ctx, route.Map{
-			ID:       msg.ChannelID,
-			Sections: m.Sections,
-			MaxPaths: m.Paths,
+			ID:             msg.ChannelID,
+			Sections:       m.Sections,
+			MaxPaths:       m.Paths,
+			Capacity:       m.Capacity,
+			Locked:         prev.Locked,
+			Format:         prev.Format,
+			NameMode:       prev.NameMode,
+			Approval:       prev.Approval,
+			AlertChannelID: prev.AlertChannelID,
+			AlertBefore:    prev.AlertBefore,
 		})
 		if err != nil {
 			return SystemError{
@@ -115,9 +135,12 @@ func (m *_map) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *ro
 			}
 		}
 
-		info := newInfoEmbed()
-		info.Description = "All routes have been purged and a new map has been saved for this channel"
-		sess.ChannelMessageSendEmbed(msg.ChannelID, info)
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	bu.Request(msg.ChannelID, "All routes have been purged and a new map has been saved for this channel")
+	return nil
 }
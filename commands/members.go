@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// MemberCache remembers guild members so boards can show names without asking discord
+// about every linked user each time a board is rendered
+type MemberCache struct {
+	mu      sync.Mutex
+	clock   Clock
+	ttl     time.Duration
+	entries map[string]cachedMember
+}
+
+type cachedMember struct {
+	member  *discordgo.Member
+	left    bool
+	fetched time.Time
+}
+
+// NewMemberCache creates a MemberCache that keeps members for ttl
+func NewMemberCache(clock Clock, ttl time.Duration) *MemberCache {
+	return &MemberCache{
+		clock:   clock,
+		ttl:     ttl,
+		entries: map[string]cachedMember{},
+	}
+}
+
+// Lookup returns the member for the user in the guild. left is true when the user is no
+// longer in the guild, in which case the member only holds what is known about the user
+func (mc *MemberCache) Lookup(sess *discordgo.Session, guildID, userID string) (mem *discordgo.Member, left bool, err error) {
+	key := guildID + ":" + userID
+
+	mc.mu.Lock()
+	e, ok := mc.entries[key]
+	mc.mu.Unlock()
+	if ok && mc.clock.Now().Sub(e.fetched) < mc.ttl {
+		return e.member, e.left, nil
+	}
+
+	mem, err = sess.State.Member(guildID, userID)
+	if err == discordgo.ErrStateNotFound {
+		mem, err = sess.GuildMember(guildID, userID)
+	}
+
+	// Looking up the user on their own when they are no longer in the guild
+	if isUnknownMember(err) {
+		left = true
+		mem = &discordgo.Member{GuildID: guildID, User: &discordgo.User{ID: userID, Username: userID}}
+		if ok && e.member != nil {
+			mem.User = e.member.User
+		} else if u, uerr := sess.User(userID); uerr == nil {
+			mem.User = u
+		}
+		err = nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	mc.mu.Lock()
+	mc.entries[key] = cachedMember{member: mem, left: left, fetched: mc.clock.Now()}
+	mc.mu.Unlock()
+
+	return mem, left, nil
+}
+
+// isUnknownMember returns true if the error is from discord saying the member is not in the guild
+func isUnknownMember(err error) bool {
+	var re *discordgo.RESTError
+	return errors.As(err, &re) && re.Message != nil &&
+		(re.Message.Code == discordgo.ErrCodeUnknownMember || re.Message.Code == discordgo.ErrCodeUnknownUser)
+}
@@ -0,0 +1,374 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/audit"
+	"github.com/duke605/NickFury/route"
+)
+
+// Invocation holds everything known about a single command being run and is
+// shared by every middleware in the pipeline
+type Invocation struct {
+	Session      *discordgo.Session
+	Message      *discordgo.MessageCreate
+	RouteService *route.Service
+	Context      *kong.Context
+	Start        time.Time
+
+	mapOnce sync.Once
+	m       route.Map
+	mapErr  error
+}
+
+// NewInvocation creates a new Invocation for the message
+func NewInvocation(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, start time.Time) *Invocation {
+	return &Invocation{
+		Session:      sess,
+		Message:      msg,
+		RouteService: rs,
+		Start:        start,
+	}
+}
+
+// Map returns the map for the channel the command was run in. The map is only looked
+// up once no matter how many times this is called
+func (inv *Invocation) Map() (route.Map, error) {
+	inv.mapOnce.Do(func() {
+		inv.m, inv.mapErr = getChannelMap(context.Background(), inv.RouteService, inv.Message.ChannelID)
+	})
+
+	return inv.m, inv.mapErr
+}
+
+// Command returns the command that was selected by the parser or nil if the parser has
+// not been run yet
+func (inv *Invocation) Command() interface{} {
+	if inv.Context == nil || inv.Context.Selected() == nil {
+		return nil
+	}
+
+	return inv.Context.Selected().Target.Addr().Interface()
+}
+
+// Handler runs a command
+type Handler func(inv *Invocation) error
+
+// Middleware wraps a handler with behaviour that runs around the command
+type Middleware func(next Handler) Handler
+
+// Chain wraps the handler in the middlewares. The first middleware provided is the
+// outermost and will be the first to run
+func Chain(h Handler, mws ...Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+
+	return h
+}
+
+// Run is the handler that runs the selected command
+func Run(inv *Invocation) error {
+	return inv.Context.Run()
+}
+
+// restricted is implemented by commands that can only be used by officers. Returning
+// false lets anyone use the command for that invocation
+type restricted interface {
+	restricted() bool
+}
+
+// lockable is implemented by commands that change routes. Returning true stops the command
+// from running while the channel's routes are locked
+type lockable interface {
+	lockable() bool
+}
+
+// audited is implemented by commands that change routes or maps. Returning true records the
+// changes the command makes in the audit log
+type audited interface {
+	audited() bool
+}
+
+// auditor is implemented by commands that add their own details to the audit log entry
+// for their changes
+type auditor interface {
+	audit(e *audit.Entry)
+}
+
+// Authorize stops commands that are restricted from being run by users that are not officers
+func Authorize() Middleware {
+	return func(next Handler) Handler {
+		return func(inv *Invocation) error {
+			if r, ok := inv.Command().(restricted); ok && r.restricted() {
+				if err := checkOfficer(inv.Session, inv.Message); err != nil {
+					return err
+				}
+			}
+
+			return next(inv)
+		}
+	}
+}
+
+// CheckLock stops members from running commands that change routes while the channel is
+// locked. Officers are not stopped
+func CheckLock() Middleware {
+	return func(next Handler) Handler {
+		return func(inv *Invocation) error {
+			l, ok := inv.Command().(lockable)
+			if !ok || !l.lockable() {
+				return next(inv)
+			}
+
+			m, err := inv.Map()
+			if err != nil {
+				return err
+			}
+
+			if !m.Locked {
+				return next(inv)
+			}
+
+			// Officers can still change their own routes while the channel is locked
+			err = checkOfficer(inv.Session, inv.Message)
+			if errors.As(err, new(PermissionError)) {
+				return Warning{
+					Message: "Routes for this channel are locked. Ask an officer to make changes for you",
+				}
+			} else if err != nil {
+				return err
+			}
+
+			return next(inv)
+		}
+	}
+}
+
+// RateLimit stops users from running more commands than the limiter allows
+func RateLimit(rl *RateLimiter) Middleware {
+	return func(next Handler) Handler {
+		return func(inv *Invocation) error {
+			if !rl.Allow(inv.Message.Author.ID, inv.Start) {
+				return Warning{
+					Message: "You are running commands too quickly. Please wait a moment and try again",
+				}
+			}
+
+			return next(inv)
+		}
+	}
+}
+
+// Log prints the command that was run, how long it took and the error it returned
+func Log() Middleware {
+	return func(next Handler) Handler {
+		return func(inv *Invocation) error {
+			err := next(inv)
+
+			status := "OK"
+			if err != nil {
+				status = err.Error()
+			}
+			fmt.Printf("%s ran %q in %s: %s\n", inv.Message.Author.ID, inv.Context.Command(), time.Since(inv.Start), status)
+
+			return err
+		}
+	}
+}
+
+// TranslateErrors turns errors and panics that can not be shown to the user into system errors
+func TranslateErrors() Middleware {
+	return func(next Handler) Handler {
+		return func(inv *Invocation) (err error) {
+			defer func() {
+				if perr := recover(); perr != nil {
+					err = SystemError{
+						error:   fmt.Errorf("%v", perr),
+						Message: "Something went wrong running the command",
+						Stack:   debug.Stack(),
+					}
+				}
+			}()
+
+			err = next(inv)
+			if err == nil || isUserFacing(err) {
+				return err
+			}
+
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong running the command",
+				Stack:   debug.Stack(),
+			}
+		}
+	}
+}
+
+// isUserFacing returns true if the error is one that can be shown to the user
+func isUserFacing(err error) bool {
+	return errors.As(err, new(UsageError)) ||
+		errors.As(err, new(SystemError)) ||
+		errors.As(err, new(Warning)) ||
+		errors.As(err, new(PermissionError))
+}
+
+// RateLimiter limits the number of commands each user can run in a window of time
+type RateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	hits   map[string][]time.Time
+}
+
+// NewRateLimiter creates a RateLimiter that allows limit commands per window for each user
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:  limit,
+		window: window,
+		hits:   map[string][]time.Time{},
+	}
+}
+
+// Allow records a command for the user at the time and returns false if the user has
+// exceeded their limit
+func (rl *RateLimiter) Allow(userID string, now time.Time) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	// Forgetting commands that are outside of the window
+	hits := rl.hits[userID][:0]
+	for _, t := range rl.hits[userID] {
+		if now.Sub(t) < rl.window {
+			hits = append(hits, t)
+		}
+	}
+
+	if len(hits) >= rl.limit {
+		rl.hits[userID] = hits
+		return false
+	}
+
+	rl.hits[userID] = append(hits, now)
+	return true
+}
+
+// Serialize runs commands in the same channel one at a time so their changes and board
+// updates can not interleave. Commands in different channels still run in parallel. It has
+// to come before Parse as parsing looks up the channel's map
+func Serialize(km *KeyedMutex) Middleware {
+	return func(next Handler) Handler {
+		return func(inv *Invocation) error {
+			unlock := km.Lock(inv.Message.ChannelID)
+			defer unlock()
+
+			return next(inv)
+		}
+	}
+}
+
+// Parse parses the arguments into the command to run. The commands' hooks are run while
+// parsing so the map they validate against is the one the command runs with
+func Parse(k *kong.Kong, args []string) Middleware {
+	return func(next Handler) Handler {
+		return func(inv *Invocation) error {
+			kctx, err := k.Parse(args)
+			if err != nil {
+				return err
+			}
+
+			inv.Context = kctx
+			return next(inv)
+		}
+	}
+}
+
+// Audit records the changes commands make to the channel's routes and map in the audit log.
+// Only commands that are audited have their changes recorded
+func Audit(ar *audit.Repository) Middleware {
+	return func(next Handler) Handler {
+		return func(inv *Invocation) error {
+			if a, ok := inv.Command().(audited); !ok || !a.audited() {
+				return next(inv)
+			}
+
+			before, err := takeSnapshot(context.Background(), inv.RouteService, inv.Message.ChannelID)
+			if err != nil {
+				return SystemError{
+					error:   err,
+					Message: "Something went wrong getting the routes for this channel",
+					Stack:   debug.Stack(),
+				}
+			}
+
+			// Recording changes even when the command fails since it may have failed after
+			// making its changes
+			err = next(inv)
+			e := audit.Entry{
+				Time:      inv.Start,
+				Action:    strings.Fields(inv.Context.Command())[0],
+				ActorID:   inv.Message.Author.ID,
+				ChannelID: inv.Message.ChannelID,
+				GuildID:   inv.Message.GuildID,
+				MessageID: inv.Message.ID,
+			}
+			if a, ok := inv.Command().(auditor); ok {
+				a.audit(&e)
+			}
+			recordChange(ar, inv.RouteService, e, before)
+
+			return err
+		}
+	}
+}
+
+// KeyedMutex is a set of mutexes that are created when a key is first locked and
+// removed once nothing holds or is waiting on the key
+type KeyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+// NewKeyedMutex creates a new KeyedMutex
+func NewKeyedMutex() *KeyedMutex {
+	return &KeyedMutex{
+		locks: map[string]*keyedLock{},
+	}
+}
+
+// Lock locks the key and returns a function that unlocks it
+func (km *KeyedMutex) Lock(key string) func() {
+	km.mu.Lock()
+	l, ok := km.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		km.locks[key] = l
+	}
+	l.refs++
+	km.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		km.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(km.locks, key)
+		}
+		km.mu.Unlock()
+	}
+}
@@ -0,0 +1,220 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/boltdb/bolt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/datastore"
+	"github.com/duke605/NickFury/route"
+)
+
+// checkKeyedMutexEmpty fails the test if the keyed mutex still has locks for any keys
+func checkKeyedMutexEmpty(t *testing.T, km *KeyedMutex) {
+	t.Helper()
+
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	if len(km.locks) != 0 {
+		t.Fatalf("got %d keys left in the keyed mutex, want 0", len(km.locks))
+	}
+}
+
+func TestKeyedMutexSameKey(t *testing.T) {
+	const n = 50
+	km := NewKeyedMutex()
+
+	var active, maxActive int32
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock := km.Lock("channel")
+			defer unlock()
+
+			now := atomic.AddInt32(&active, 1)
+			for {
+				max := atomic.LoadInt32(&maxActive)
+				if now <= max || atomic.CompareAndSwapInt32(&maxActive, max, now) {
+					break
+				}
+			}
+
+			// Giving other goroutines a chance to enter the critical section
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&active, -1)
+		}()
+	}
+	wg.Wait()
+
+	if maxActive != 1 {
+		t.Fatalf("got %d goroutines holding the same key at once, want 1", maxActive)
+	}
+	checkKeyedMutexEmpty(t, km)
+}
+
+func TestKeyedMutexDifferentKeys(t *testing.T) {
+	const n = 50
+	km := NewKeyedMutex()
+
+	// Every goroutine holds its key until all of them hold their keys which can only
+	// happen when locks on different keys overlap
+	held := sync.WaitGroup{}
+	held.Add(n)
+	all := make(chan struct{})
+	go func() {
+		held.Wait()
+		close(all)
+	}()
+
+	overlapped := int32(0)
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+
+			unlock := km.Lock(key)
+			defer unlock()
+
+			held.Done()
+			select {
+			case <-all:
+				atomic.AddInt32(&overlapped, 1)
+			case <-time.After(5 * time.Second):
+			}
+		}(fmt.Sprintf("channel%d", i))
+	}
+	wg.Wait()
+
+	if overlapped != n {
+		t.Fatalf("got %d goroutines holding their keys at once, want %d", overlapped, n)
+	}
+	checkKeyedMutexEmpty(t, km)
+}
+
+// openTestDB opens a database from testdata. Bolt's writes fail the race detector's pointer
+// checks so tests only read from databases that were written ahead of time
+func openTestDB(t *testing.T, name string) *bolt.DB {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join("testdata", name), 0600, &bolt.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("opening %s: %v", name, err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// waitForWaiters waits until n callers hold or are waiting on the key
+func waitForWaiters(t *testing.T, km *KeyedMutex, key string, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		km.mu.Lock()
+		l, ok := km.locks[key]
+		refs := 0
+		if ok {
+			refs = l.refs
+		}
+		km.mu.Unlock()
+
+		if refs >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("timed out waiting for %d callers on %q", n, key)
+}
+
+func TestSerializeParsesAfterLock(t *testing.T) {
+
+	// Both databases have a map for "channel" but it is only locked in one of them. Switching
+	// the datastore to the locked database stands in for an officer running lock
+	store := &datastore.Datastore{DB: openTestDB(t, "unlocked.db")}
+	locked := openTestDB(t, "locked.db")
+	rs := route.NewService(&route.Repository{Datastore: store})
+
+	sess := &discordgo.Session{State: discordgo.NewState()}
+	sess.State.GuildAdd(&discordgo.Guild{ID: "guild"})
+	sess.State.MemberAdd(&discordgo.Member{GuildID: "guild", User: &discordgo.User{ID: "member"}})
+
+	// link runs a member's link command through the middlewares that look at the map and
+	// reports if the command was run
+	km := NewKeyedMutex()
+	link := func() (bool, error) {
+		msg := &discordgo.MessageCreate{Message: &discordgo.Message{
+			ChannelID: "channel",
+			GuildID:   "guild",
+			Author:    &discordgo.User{ID: "member"},
+		}}
+		inv := NewInvocation(sess, msg, rs, time.Now())
+		parser, err := kong.New(&Root{},
+			kong.Exit(func(int) {}),
+			kong.TypeMapper(reflect.TypeOf(RouteSelectors{}), kong.MapperFunc(RouteSelectorsMapper)),
+			kong.Bind(inv),
+			kong.Bind(sess),
+			kong.Bind(msg),
+			kong.Bind(rs),
+		)
+		if err != nil {
+			return false, err
+		}
+
+		ran := false
+		err = Chain(func(*Invocation) error { ran = true; return nil },
+			Serialize(km),
+			Parse(parser, []string{"link", "1A"}),
+			CheckLock(),
+		)(inv)
+
+		return ran, err
+	}
+
+	if ran, err := link(); !ran || err != nil {
+		t.Fatalf("got ran = %t and error %v linking while unlocked, want the command to run", ran, err)
+	}
+
+	// The channel is locked while the member's command is waiting to be parsed
+	unlock := km.Lock("channel")
+	type result struct {
+		ran bool
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		ran, err := link()
+		done <- result{ran, err}
+	}()
+	waitForWaiters(t, km, "channel", 2)
+
+	store.DB = locked
+	unlock()
+
+	select {
+	case res := <-done:
+		if res.ran {
+			t.Fatal("the command ran after the channel was locked")
+		}
+		if !errors.As(res.err, new(Warning)) {
+			t.Fatalf("got error %v, want a warning that the channel is locked", res.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the command")
+	}
+	checkKeyedMutexEmpty(t, km)
+}
@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/route"
+)
+
+// Limits discord puts on embeds
+const (
+	maxEmbedFields = 25
+	maxEmbedSize   = 6000
+)
+
+type mine struct {
+	DM bool `name:"dm" help:"Sends your routes to you in a direct message"`
+}
+
+func (m mine) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service) error {
+	ctx := context.Background()
+	routes, err := rs.GetRoutesForUser(ctx, msg.Author.ID)
+	if err != nil {
+		return SystemError{
+			error:   err,
+			Message: "Something went wrong getting your linked routes",
+			Stack:   debug.Stack(),
+		}
+	}
+
+	channels, err := guildChannels(sess, msg.GuildID)
+	if err != nil {
+		return SystemError{
+			error:   err,
+			Message: "Something went wrong getting the channels in this server",
+			Stack:   debug.Stack(),
+		}
+	}
+
+	// Grouping the routes by the channels in this guild they are linked in
+	byChannel := map[string][]route.Route{}
+	for _, r := range routes {
+		byChannel[r.ChannelID] = append(byChannel[r.ChannelID], r)
+	}
+
+	fields := []*discordgo.MessageEmbedField{}
+	for _, ch := range sortedChannels(channels) {
+		linked, ok := byChannel[ch.ID]
+		if !ok {
+			continue
+		}
+
+		field, err := mineField(ctx, rs, ch, linked)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return Warning{
+			Message: "You are not linked to any routes in this server",
+		}
+	}
+	embeds := composeMineEmbeds(fields)
+
+	if !m.DM {
+		for _, e := range embeds {
+			_, err = sess.ChannelMessageSendEmbed(msg.ChannelID, e)
+			if err != nil {
+				return SystemError{
+					error:   err,
+					Message: "Something went wrong sending your routes",
+					Stack:   debug.Stack(),
+				}
+			}
+		}
+
+		return nil
+	}
+
+	// Sending the routes privately and letting the user know where to find them
+	dm, err := sess.UserChannelCreate(msg.Author.ID)
+	if err == nil {
+		for _, e := range embeds {
+			if _, err = sess.ChannelMessageSendEmbed(dm.ID, e); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return Warning{
+			Message: "I couldn't send you a direct message. Check that you allow direct messages from server members",
+		}
+	}
+	sess.MessageReactionAdd(msg.ChannelID, msg.ID, "📬")
+
+	return nil
+}
+
+// composeMineEmbeds splits the fields across as many embeds as are needed to stay within
+// discord's limits. Only the first embed has a title
+func composeMineEmbeds(fields []*discordgo.MessageEmbedField) []*discordgo.MessageEmbed {
+	first := newInfoEmbed()
+	first.Title = "Your routes"
+	embeds := []*discordgo.MessageEmbed{first}
+
+	size := route.EmbedSize(first)
+	for _, f := range fields {
+		embed := embeds[len(embeds)-1]
+		fsize := utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+
+		// Starting a new embed when the field does not fit in the current one
+		if len(embed.Fields) > 0 && (len(embed.Fields) == maxEmbedFields || size+fsize > maxEmbedSize) {
+			embed = newInfoEmbed()
+			embeds = append(embeds, embed)
+			size = route.EmbedSize(embed)
+		}
+
+		embed.Fields = append(embed.Fields, f)
+		size += fsize
+	}
+
+	return embeds
+}
+
+// mineField describes the user's routes in the channel along with the channel's map
+func mineField(ctx context.Context, rs *route.Service, ch *discordgo.Channel, linked []route.Route) (*discordgo.MessageEmbedField, error) {
+	m, err := rs.GetMapForChannel(ctx, ch.ID)
+	if err != nil {
+		return nil, SystemError{
+			error:   err,
+			Message: "Something went wrong getting the map for a channel",
+			Stack:   debug.Stack(),
+		}
+	}
+
+	routes, err := getChannelRoutes(ctx, rs, ch.ID)
+	if err != nil {
+		return nil, err
+	}
+	idx := route.IndexRoutes(routes)
+
+	sort.SliceStable(linked, func(i, j int) bool {
+		if linked[i].Section != linked[j].Section {
+			return linked[i].Section < linked[j].Section
+		}
+
+		return linked[i].Path < linked[j].Path
+	})
+
+	header := fmt.Sprintf("<#%s> · %d section(s)", ch.ID, m.Sections)
+	if m.Locked {
+		header += " · locked"
+	}
+
+	lines := []string{header}
+	for _, r := range linked {
+		primary, _, _ := route.SplitRoutes(idx[route.RouteKey(r.Section, r.Path)])
+		line := fmt.Sprintf("**%s** %d/%d linked", RouteRef{Section: r.Section, Path: r.Path}, len(primary), m.PathCapacity())
+
+		switch {
+		case r.Pending:
+			line += " *(pending approval)*"
+		case r.Standby:
+			line += " *(standby)*"
+		case r.Reserved:
+			line += fmt.Sprintf(" *(reserved until <t:%d:t>)*", r.ExpiresAt.Unix())
+		}
+		if r.Note != "" {
+			line += fmt.Sprintf(" *(%s)*", route.EscapeMarkdown(r.Note))
+		}
+
+		lines = append(lines, line)
+	}
+
+	return &discordgo.MessageEmbedField{
+		Name:  "#" + ch.Name,
+		Value: truncate(strings.Join(lines, "\n"), 1024),
+	}, nil
+}
+
+// sortedChannels returns the channels in the order they are shown in discord
+func sortedChannels(channels []*discordgo.Channel) []*discordgo.Channel {
+	sorted := append([]*discordgo.Channel{}, channels...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Position != sorted[j].Position {
+			return sorted[i].Position < sorted[j].Position
+		}
+
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	return sorted
+}
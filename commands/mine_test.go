@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/route"
+)
+
+func TestComposeMineEmbeds(t *testing.T) {
+
+	// fields creates n fields with values of the length provided
+	fields := func(n, length int) []*discordgo.MessageEmbedField {
+		fs := []*discordgo.MessageEmbedField{}
+		for i := 0; i < n; i++ {
+			fs = append(fs, &discordgo.MessageEmbedField{Name: "#c", Value: strings.Repeat("x", length)})
+		}
+
+		return fs
+	}
+
+	tests := []struct {
+		name       string
+		fields     []*discordgo.MessageEmbedField
+		wantFields []int
+	}{
+		{name: "25 fields", fields: fields(25, 10), wantFields: []int{25}},
+		{name: "26 fields", fields: fields(26, 10), wantFields: []int{25, 1}},
+		{name: "full fields", fields: fields(6, 1024), wantFields: []int{5, 1}},
+		{name: "many full fields", fields: fields(12, 1024), wantFields: []int{5, 5, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			embeds := composeMineEmbeds(tt.fields)
+			if len(embeds) != len(tt.wantFields) {
+				t.Fatalf("got %d embeds, want %d", len(embeds), len(tt.wantFields))
+			}
+
+			for i, e := range embeds {
+				if len(e.Fields) != tt.wantFields[i] {
+					t.Errorf("embed %d has %d fields, want %d", i, len(e.Fields), tt.wantFields[i])
+				}
+				if size := route.EmbedSize(e); size > maxEmbedSize {
+					t.Errorf("embed %d has a size of %d, want at most %d", i, size, maxEmbedSize)
+				}
+			}
+		})
+	}
+}
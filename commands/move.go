@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/boltdb/bolt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/route"
+)
+
+type move struct {
+	From RouteRef `arg:"" name:"from" help:"The route you are currently linked to (eg. 1A)"`
+	To   RouteRef `arg:"" name:"to" help:"The route to move yourself to (eg. 2C)"`
+
+	User Mention `name:"user" help:"Sets the user that will be moved"`
+}
+
+// restricted only allows officers to change the routes of other users
+func (mv *move) restricted() bool { return mv.User != "" }
+
+// lockable stops members from changing their own routes while the channel is locked
+func (mv *move) lockable() bool { return mv.User == "" }
+
+func (mv *move) audited() bool { return true }
+
+func (mv *move) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, m route.Map, bu *BoardUpdater) error {
+	var routes []route.Route
+	var promoted []route.Route
+	var err error
+
+	userID := msg.Author.ID
+	if mv.User != "" {
+		userID = string(mv.User)
+	}
+
+	// Moving would let members onto routes officers have not approved
+	if m.Approval && mv.User == "" {
+		officer, err := isUserOrRole(sess, msg.GuildID, msg.Author.ID, trustedUsers, trustedRoles)
+		if err != nil {
+			return err
+		}
+		if !officer {
+			return Warning{
+				Message: fmt.Sprintf("Links in this channel need to be approved by an officer. Unlink from %s and link to %s to request it", mv.From, mv.To),
+			}
+		}
+	}
+
+	now := messageTime(msg)
+	ctx := context.Background()
+	err = rs.InTransaction(ctx, true, func(ctx context.Context, _ *bolt.Tx) error {
+
+		// Getting the already selected routes for the channel
+		routes, err = getChannelRoutes(ctx, rs, msg.ChannelID)
+		if err != nil {
+			return err
+		}
+
+		// Finding the route being moved and making sure the user is not already on the destination
+		from := -1
+		for i, r := range routes {
+			if r.UserID != userID {
+				continue
+			}
+
+			if r.Section == mv.From.Section && r.Path == mv.From.Path {
+				from = i
+			} else if r.Section == mv.To.Section && r.Path == mv.To.Path {
+				m := "You are already linked to path %s in section %d"
+				if userID != msg.Author.ID {
+					m = "User is already linked to path %s in section %d"
+				}
+				return Warning{
+					Message: fmt.Sprintf(m, r.Path, r.Section),
+				}
+			}
+		}
+		if from == -1 {
+			m := "You are not linked to path %s in section %d"
+			if userID != msg.Author.ID {
+				m = "User is not linked to path %s in section %d"
+			}
+			return Warning{
+				Message: fmt.Sprintf(m, mv.From.Path, mv.From.Section),
+			}
+		}
+
+		// Making sure users on the route are not taking a place reserved for someone else
+		moved := routes[from]
+		to := route.IndexRoutes(routes)[route.RouteKey(mv.To.Section, mv.To.Path)]
+		if !moved.Standby && !moved.Pending && reservedForOthers(to, userID, m.PathCapacity(), now) {
+			return Warning{
+				Message: fmt.Sprintf("%s is reserved for someone else", mv.To),
+			}
+		}
+
+		// Replacing the old route with the moved one
+		err = rs.DeleteRoute(ctx, routes[from])
+		if err != nil {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong unlinking you from your current route",
+				Stack:   debug.Stack(),
+			}
+		}
+
+		routes[from].Section = mv.To.Section
+		routes[from].Path = mv.To.Path
+		routes[from].UpdatedAt = now
+		err = rs.InsertRoute(ctx, routes[from])
+		if err != nil {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong linking you to the new route",
+				Stack:   debug.Stack(),
+			}
+		}
+
+		// Filling the route that was left with users on standby
+		promoted, err = promoteStandbys(ctx, rs, m, msg.ChannelID, []RouteRef{mv.From}, now)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	content := fmt.Sprintf("Moved <@!%s> from **%s** to **%s**", userID, mv.From, mv.To)
+	content += notifyPromoted(sess, msg.ChannelID, promoted)
+	bu.Request(msg.ChannelID, content)
+	return nil
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/boltdb/bolt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/route"
+)
+
+type names struct {
+	Mode string `arg:"" name:"mode" enum:"mention,nickname,username" help:"How linked users are named on the route board (mention, nickname or username)"`
+}
+
+func (names) restricted() bool { return true }
+
+func (names) audited() bool { return true }
+
+func (n names) Run(msg *discordgo.MessageCreate, rs *route.Service, bu *BoardUpdater) error {
+	err := rs.InTransaction(context.Background(), true, func(ctx context.Context, _ *bolt.Tx) error {
+		m, err := getChannelMap(ctx, rs, msg.ChannelID)
+		if err != nil {
+			return err
+		}
+
+		m.NameMode = n.Mode
+		err = rs.InsertMap(ctx, m)
+		if err != nil {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong when saving the map",
+				Stack:   debug.Stack(),
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	bu.Request(msg.ChannelID, fmt.Sprintf("Linked users are now shown by %s", n.Mode))
+	return nil
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/route"
+)
+
+type note struct {
+	Route RouteRef `arg:"" name:"route" help:"The route the note is for (eg. 1A)"`
+	Text  []string `arg:"" optional:"" name:"text" help:"The note to show with the link. Leave out to remove the note"`
+
+	User Mention `name:"user" help:"Sets the user whose note will be changed"`
+}
+
+// restricted only allows officers to change the notes of other users
+func (n *note) restricted() bool { return n.User != "" }
+
+// lockable stops members from changing their own notes while the channel is locked
+func (n *note) lockable() bool { return n.User == "" }
+
+func (n *note) audited() bool { return true }
+
+func (n *note) Run(msg *discordgo.MessageCreate, rs *route.Service, bu *BoardUpdater) error {
+	userID := msg.Author.ID
+	if n.User != "" {
+		userID = string(n.User)
+	}
+
+	text, err := sanitizeNote(strings.Join(n.Text, " "), "text", "note")
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	err = rs.InTransaction(ctx, true, func(ctx context.Context, _ *bolt.Tx) error {
+		routes, err := getChannelRoutes(ctx, rs, msg.ChannelID)
+		if err != nil {
+			return err
+		}
+
+		// Finding the user's link to the route
+		for _, r := range routes {
+			if r.UserID != userID || r.Section != n.Route.Section || r.Path != n.Route.Path {
+				continue
+			}
+
+			r.Note = text
+			r.UpdatedAt = messageTime(msg)
+			err = rs.InsertRoute(ctx, r)
+			if err != nil {
+				return SystemError{
+					error:   err,
+					Message: "Something went wrong saving the note",
+					Stack:   debug.Stack(),
+				}
+			}
+
+			return nil
+		}
+
+		m := "You are not linked to %s"
+		if userID != msg.Author.ID {
+			m = "User is not linked to %s"
+		}
+		return Warning{
+			Message: fmt.Sprintf(m, n.Route),
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	content := fmt.Sprintf("Updated the note for <@!%s> on **%s**", userID, n.Route)
+	if text == "" {
+		content = fmt.Sprintf("Removed the note for <@!%s> on **%s**", userID, n.Route)
+	}
+
+	bu.Request(msg.ChannelID, content)
+	return nil
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/route"
+)
+
+type overview struct{}
+
+func (overview) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service) error {
+	ctx := context.Background()
+	channels, err := guildChannels(sess, msg.GuildID)
+	if err != nil {
+		return SystemError{
+			error:   err,
+			Message: "Something went wrong getting the channels in this server",
+			Stack:   debug.Stack(),
+		}
+	}
+
+	total := route.Coverage{}
+	fields := []*discordgo.MessageEmbedField{}
+	for _, ch := range sortedChannels(channels) {
+		m, err := rs.GetMapForChannel(ctx, ch.ID)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong getting the map for a channel",
+				Stack:   debug.Stack(),
+			}
+		}
+
+		routes, err := getChannelRoutes(ctx, rs, ch.ID)
+		if err != nil {
+			return err
+		}
+
+		c := route.ComputeCoverage(m, route.IndexRoutes(routes))
+		total = total.Add(c)
+		fields = append(fields, overviewField(ctx, rs, msg.GuildID, ch, m, c))
+	}
+	if len(fields) == 0 {
+		return Warning{
+			Message: "There are no maps configured in this server",
+		}
+	}
+
+	info := newInfoEmbed()
+	info.Title = "Raid overview"
+	info.Description = fmt.Sprintf("**%d** channel(s) · **%d%%** filled · **%d%%** complete · **%d** unassigned path(s)",
+		len(fields), total.FillPercent(), total.CompletePercent(), total.Unassigned)
+	if len(fields) > maxEmbedFields {
+		info.Footer = &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("...and %d more channel(s)", len(fields)-maxEmbedFields)}
+		fields = fields[:maxEmbedFields]
+	}
+	info.Fields = fields
+
+	sess.ChannelMessageSendEmbed(msg.ChannelID, info)
+	return nil
+}
+
+// overviewField summarises the coverage of the channel's map with a link to its route board
+func overviewField(ctx context.Context, rs *route.Service, guildID string, ch *discordgo.Channel, m route.Map, c route.Coverage) *discordgo.MessageEmbedField {
+	value := fmt.Sprintf("<#%s>", ch.ID)
+	if m.Locked {
+		value += " · \U0001F512 Locked"
+	}
+	if ids, err := rs.GetBoardMessageIDs(ctx, ch.ID); err == nil && len(ids) > 0 {
+		value += fmt.Sprintf(" · [Board](https://discord.com/channels/%s/%s/%s)", guildID, ch.ID, ids[0])
+	}
+	value += fmt.Sprintf("\nFilled **%d%%** (%d/%d) · Complete **%d%%** · Unassigned **%d**/%d path(s)",
+		c.FillPercent(), c.Filled, c.Slots, c.CompletePercent(), c.Unassigned, c.Paths)
+
+	return &discordgo.MessageEmbedField{
+		Name:  "#" + ch.Name,
+		Value: value,
+	}
+}
@@ -2,8 +2,10 @@ package commands
 
 import (
 	"context"
+	"fmt"
 	"runtime/debug"
 
+	"github.com/boltdb/bolt"
 	"github.com/bwmarrin/discordgo"
 	"github.com/duke605/NickFury/route"
 )
@@ -11,39 +13,39 @@ import (
 // Purge ...
 type Purge struct{}
 
-// AfterApply ...
-func (Purge) AfterApply(sess *discordgo.Session, m *discordgo.MessageCreate) error {
-	auth, err := isUserOrRole(sess, m.GuildID, m.Author.ID, trustedUsers, trustedRoles)
-	if err != nil {
-		return SystemError{
-			error:   err,
-			Message: "Something went wrong getting your role information",
-			Stack:   debug.Stack(),
-		}
-	}
-
-	if !auth {
-		return PermissionError{
-			Message: "You do not have permission to use this command",
-		}
-	}
+func (Purge) restricted() bool { return true }
 
-	return nil
-}
+func (Purge) audited() bool { return true }
 
 // Run ...
-func (Purge) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service) error {
-	var err error
+func (Purge) Run(msg *discordgo.MessageCreate, rs *route.Service, bu *BoardUpdater) error {
+	err := rs.InTransaction(context.Background(), true, func(ctx context.Context, _ *bolt.Tx) error {
+
+		// Keeping the routes so they can be looked back on
+		err := archiveRoutes(ctx, rs, msg.GuildID, msg.ChannelID, "purge", msg.Author.ID, messageTime(msg))
+		if err != nil {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong archiving the routes for the channel",
+				Stack:   debug.Stack(),
+			}
+		}
 
-	err = rs.DeleteAllRoutesForChannel(context.Background(), msg.ChannelID)
-	if err != nil {
-		return SystemError{
-			error:   err,
-			Message: "Something went wrong purging all linked routes for the channel",
-			Stack:   debug.Stack(),
+		err = rs.DeleteAllRoutesForChannel(ctx, msg.ChannelID)
+		if err != nil {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong purging all linked routes for the channel",
+				Stack:   debug.Stack(),
+			}
 		}
+
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	sess.ChannelMessageSend(msg.ChannelID, "All routes have been purged for this channel")
+	bu.Request(msg.ChannelID, fmt.Sprintf("All routes have been purged for this channel by <@!%s>", msg.Author.ID))
 	return nil
 }
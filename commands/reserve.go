@@ -0,0 +1,225 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/audit"
+	"github.com/duke605/NickFury/route"
+	"github.com/spf13/viper"
+)
+
+type reserve struct {
+	Route RouteRef `arg:"" name:"route" help:"The route to reserve (eg. 1C)"`
+	User  Mention  `arg:"" name:"user" help:"The user the route is reserved for"`
+	Until string   `arg:"" optional:"" name:"until" default:"2h" help:"When the reservation expires as a time (eg. 20:00) or how long it lasts (eg. 90m)"`
+}
+
+func (r *reserve) restricted() bool { return true }
+
+func (r *reserve) audited() bool { return true }
+
+func (r *reserve) Run(msg *discordgo.MessageCreate, rs *route.Service, bu *BoardUpdater) error {
+	now := messageTime(msg)
+	expires, err := parseExpiry(r.Until, "until", "reserve", now)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	err = rs.InTransaction(ctx, true, func(ctx context.Context, _ *bolt.Tx) error {
+		routes, err := getChannelRoutes(ctx, rs, msg.ChannelID)
+		if err != nil {
+			return err
+		}
+
+		reservation := route.Route{
+			ID:        msg.ID,
+			UserID:    string(r.User),
+			ChannelID: msg.ChannelID,
+			Section:   r.Route.Section,
+			Path:      r.Route.Path,
+			CreatedAt: now,
+			UpdatedAt: now,
+			Reserved:  true,
+			ExpiresAt: expires,
+		}
+
+		// Changing when the reservation expires if the route is already reserved for the user
+		for _, existing := range routes {
+			if existing.UserID != reservation.UserID || existing.Section != r.Route.Section || existing.Path != r.Route.Path {
+				continue
+			}
+			if !existing.Reserved {
+				return Warning{
+					Message: fmt.Sprintf("<@!%s> is already linked to %s", r.User, r.Route),
+				}
+			}
+
+			reservation = existing
+			reservation.UpdatedAt = now
+			reservation.ExpiresAt = expires
+		}
+
+		err = rs.InsertRoute(ctx, reservation)
+		if err != nil {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong saving the reservation",
+				Stack:   debug.Stack(),
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	bu.Request(msg.ChannelID, fmt.Sprintf("Reserved **%s** for <@!%s> until <t:%d:t>", r.Route, r.User, expires.Unix()))
+	return nil
+}
+
+// parseExpiry reads when something expires from a time of day (eg. 20:00) in the configured
+// timezone or a duration (eg. 90m) from now. param is the name of the argument the text was
+// provided with and cmd is the name of the command being run
+func parseExpiry(text, param, cmd string, now time.Time) (time.Time, error) {
+	cmdPrefix := viper.GetString("COMMAND_PREFIX")
+
+	if d, err := time.ParseDuration(text); err == nil && d > 0 {
+		return now.Add(d), nil
+	}
+
+	loc, err := time.LoadLocation(viper.GetString("TIMEZONE"))
+	if err != nil {
+		loc = time.UTC
+	}
+
+	// Using the next time the time of day comes around
+	if t, err := time.Parse("15:04", text); err == nil {
+		local := now.In(loc)
+		expires := time.Date(local.Year(), local.Month(), local.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+		if !expires.After(now) {
+			expires = expires.AddDate(0, 0, 1)
+		}
+
+		return expires, nil
+	}
+
+	return time.Time{}, UsageError{
+		Param:    param,
+		Message:  "Must be a time (eg. 20:00) or a duration (eg. 90m)",
+		Provided: text,
+		Footer:   fmt.Sprintf("Type %s%s --help for command usage", cmdPrefix, cmd),
+	}
+}
+
+// reservedForOthers returns true if the path is full and is reserved for someone other
+// than the user
+func reservedForOthers(routes []route.Route, userID string, capacity int, now time.Time) bool {
+	taken, reserved := 0, false
+	for _, r := range routes {
+		if r.Standby || r.Pending || r.IsExpired(now) {
+			continue
+		}
+
+		taken++
+		if r.Reserved && r.UserID != userID {
+			reserved = true
+		}
+	}
+
+	return reserved && taken >= capacity
+}
+
+// ExpireReservations removes reservations that were not claimed in time and updates the
+// boards of the channels they were in. Each channel is locked while its reservations are
+// removed so the changes are not mixed up with a command running in the channel
+func ExpireReservations(sess *discordgo.Session, rs *route.Service, ar *audit.Repository, bu *BoardUpdater, km *KeyedMutex, now time.Time) {
+	expired, err := rs.GetExpiredReservations(context.Background(), now)
+	if err != nil {
+		fmt.Println("Error occured getting expired reservations: ", err)
+		return
+	}
+
+	byChannel := map[string][]route.Route{}
+	for _, r := range expired {
+		byChannel[r.ChannelID] = append(byChannel[r.ChannelID], r)
+	}
+
+	for channelID := range byChannel {
+		expireChannelReservations(sess, rs, ar, bu, km, channelID, now)
+	}
+}
+
+// expireChannelReservations removes the reservations in the channel that have expired
+func expireChannelReservations(sess *discordgo.Session, rs *route.Service, ar *audit.Repository, bu *BoardUpdater, km *KeyedMutex, channelID string, now time.Time) {
+	unlock := km.Lock(channelID)
+	defer unlock()
+
+	ctx := context.Background()
+	before, err := takeSnapshot(ctx, rs, channelID)
+	if err != nil {
+		fmt.Println("Error occured taking a snapshot for the audit log: ", err)
+		return
+	}
+
+	note := ""
+	promoted := []route.Route{}
+	err = rs.InTransaction(ctx, true, func(ctx context.Context, _ *bolt.Tx) error {
+
+		// Getting the reservations again since a command may have claimed them before the
+		// channel was locked
+		routes, err := rs.GetRoutesInChannel(ctx, channelID)
+		if err != nil {
+			return err
+		}
+
+		freed := []RouteRef{}
+		for _, r := range routes {
+			if !r.IsExpired(now) {
+				continue
+			}
+
+			err := rs.DeleteRoute(ctx, r)
+			if err != nil {
+				return err
+			}
+
+			ref := RouteRef{Section: r.Section, Path: r.Path}
+			freed = append(freed, ref)
+			note += fmt.Sprintf("\nReservation of **%s** for <@!%s> expired", ref, r.UserID)
+		}
+		if len(freed) == 0 {
+			return nil
+		}
+
+		// Filling the freed routes with users on standby. Channels without a map have no
+		// paths to promote users to
+		m, err := rs.GetMapForChannel(ctx, channelID)
+		if err == sql.ErrNoRows {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		promoted, err = promoteStandbys(ctx, rs, m, channelID, freed, now)
+		return err
+	})
+	if err != nil {
+		fmt.Println("Error occured expiring reservations: ", err)
+		return
+	}
+	if note == "" {
+		return
+	}
+	note += notifyPromoted(sess, channelID, promoted)
+
+	recordChange(ar, rs, audit.Entry{Time: now, Action: "expire", ChannelID: channelID, GuildID: channelGuildID(sess, channelID)}, before)
+	bu.Request(channelID, note[1:])
+}
@@ -1,64 +1,87 @@
 package commands
 
 import (
+	"bytes"
 	"context"
-	"database/sql"
-	"fmt"
+	"image/png"
 	"runtime/debug"
 
-	"github.com/alecthomas/kong"
 	"github.com/bwmarrin/discordgo"
 	"github.com/duke605/NickFury/route"
-	"github.com/spf13/viper"
 )
 
-type show struct{}
+type show struct {
+	New   bool `name:"new" help:"Posts a new board instead of updating the existing one"`
+	Image bool `name:"image" help:"Shows the routes as an image"`
+	Text  bool `name:"text" help:"Shows the routes as a plain text table"`
+}
+
+func (s show) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, mc *MemberCache, m route.Map, bu *BoardUpdater) error {
+	if !s.New && !s.Image && !s.Text {
+		bu.Request(msg.ChannelID, "")
+		return nil
+	}
+
+	// Posting a new route list to the channel
+	if !s.Image && !s.Text {
+		bu.Repost(msg.ChannelID, "")
+		return nil
+	}
 
-func (show) AfterApply(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, k *kong.Kong) error {
-	cmdPrefix := viper.GetString("COMMAND_PREFIX")
+	routes, err := getChannelRoutes(context.Background(), rs, msg.ChannelID)
+	if err != nil {
+		return err
+	}
 
-	// Checking if a map exists for the channel
-	m, err := rs.GetMapForChannel(context.Background(), msg.ChannelID)
-	if err != nil && err != sql.ErrNoRows {
+	if s.Image {
+		return s.sendImage(sess, msg, rs, mc, m, routes)
+	}
+
+	return s.sendText(sess, msg, rs, mc, m, routes)
+}
+
+// sendImage sends the routes to the channel as an image
+func (show) sendImage(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, mc *MemberCache, m route.Map, routes []route.Route) error {
+	buf := bytes.Buffer{}
+	img := rs.ComposeImage(m, route.IndexRoutes(routes), displayNames(sess, mc, msg.GuildID, plainNameMode(m.NameMode), routes))
+	if err := png.Encode(&buf, img); err != nil {
 		return SystemError{
 			error:   err,
-			Message: "Something went wrong getting the map for this channel",
+			Message: "Something went wrong drawing the routes",
 			Stack:   debug.Stack(),
 		}
-	} else if err == sql.ErrNoRows {
-		return Warning{
-			Message: fmt.Sprintf("There is no map configured for this channel. Use the `%smap` command to configure one", cmdPrefix),
-		}
 	}
 
-	return kong.Bind(m).Apply(k)
-}
-
-func (show) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, m route.Map) error {
-	var routes []route.Route
-	var err error
-
-	ctx := context.Background()
-	routes, err = rs.GetRoutesInChannel(ctx, msg.ChannelID)
+	_, err := sess.ChannelMessageSendComplex(msg.ChannelID, &discordgo.MessageSend{
+		Files: []*discordgo.File{{
+			Name:        "routes.png",
+			ContentType: "image/png",
+			Reader:      &buf,
+		}},
+	})
 	if err != nil {
 		return SystemError{
 			error:   err,
-			Message: "Something went wrong getting linked routes for channel",
+			Message: "Something went wrong sending the routes image",
 			Stack:   debug.Stack(),
 		}
 	}
 
-	// Creating a map for quick lookup of assigned routes
-	idx := map[string][]string{}
-	for _, r := range routes {
-		key := fmt.Sprintf("%d:%s", r.Section, r.Path)
-		idx[key] = append(idx[key], r.UserID)
-	}
+	return nil
+}
 
-	// Sending route list to channel and then cleaning up previous lists
-	newMsg, err := sess.ChannelMessageSendEmbed(msg.ChannelID, rs.ComposeEmbed(m, idx))
-	if err == nil {
-		cleanupPreviousRouteEmbeds(sess, newMsg.ChannelID, newMsg.ID)
+// sendText sends the routes to the channel as a plain text table
+func (show) sendText(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, mc *MemberCache, m route.Map, routes []route.Route) error {
+	m.Format = route.FormatText
+	for _, p := range composeBoardPages(sess, rs, mc, m, msg.GuildID, "", routes) {
+		_, err := sess.ChannelMessageSend(msg.ChannelID, p.content)
+		if err != nil {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong sending the routes table",
+				Stack:   debug.Stack(),
+			}
+		}
 	}
 
 	return nil
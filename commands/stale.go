@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/route"
+)
+
+type stale struct {
+	Age time.Duration `arg:"" optional:"" name:"age" default:"24h" help:"How long a link must go unchanged to be stale (eg. 12h or 90m)"`
+
+	Unlink bool `name:"unlink" help:"Unlinks the stale links"`
+}
+
+// restricted only allows officers to unlink stale links
+func (s *stale) restricted() bool { return s.Unlink }
+
+// audited records the links that were unlinked
+func (s *stale) audited() bool { return s.Unlink }
+
+func (s *stale) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, m route.Map, bu *BoardUpdater) error {
+	var found []route.Route
+	var promoted []route.Route
+	now := messageTime(msg)
+
+	ctx := context.Background()
+	err := rs.InTransaction(ctx, true, func(ctx context.Context, _ *bolt.Tx) error {
+		routes, err := getChannelRoutes(ctx, rs, msg.ChannelID)
+		if err != nil {
+			return err
+		}
+
+		// Finding links that have not changed within the age. Reservations expire on their own
+		// and links from before times were recorded are left out since their age is unknown
+		for _, r := range routes {
+			changed := r.LastChanged()
+			if !r.Reserved && !changed.IsZero() && now.Sub(changed) >= s.Age {
+				found = append(found, r)
+			}
+		}
+		if len(found) == 0 {
+			return Warning{
+				Message: fmt.Sprintf("No links have gone unchanged for %s or longer", formatAge(s.Age)),
+			}
+		}
+
+		if !s.Unlink {
+			return nil
+		}
+
+		freed := make([]RouteRef, len(found))
+		for i, r := range found {
+			err = rs.DeleteRoute(ctx, r)
+			if err != nil {
+				return SystemError{
+					error:   err,
+					Message: "Something went wrong unlinking stale links",
+					Stack:   debug.Stack(),
+				}
+			}
+			freed[i] = RouteRef{Section: r.Section, Path: r.Path}
+		}
+
+		// Filling the freed routes with users on standby
+		promoted, err = promoteStandbys(ctx, rs, m, msg.ChannelID, freed, now)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.Unlink {
+		note := fmt.Sprintf("Unlinked **%d** link(s) unchanged for %s or longer", len(found), formatAge(s.Age))
+		bu.Request(msg.ChannelID, note+notifyPromoted(sess, msg.ChannelID, promoted))
+		return nil
+	}
+
+	// Listing the oldest links first
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].LastChanged().Before(found[j].LastChanged())
+	})
+
+	lines := make([]string, len(found))
+	for i, r := range found {
+		ref := RouteRef{Section: r.Section, Path: r.Path}
+		lines[i] = fmt.Sprintf("**%s** <@!%s> (%s)", ref, r.UserID, formatAge(now.Sub(r.LastChanged())))
+	}
+
+	info := newInfoEmbed()
+	info.Title = fmt.Sprintf("Links unchanged for %s or longer", formatAge(s.Age))
+	info.Description = truncate(strings.Join(lines, "\n"), 2048)
+	sess.ChannelMessageSendEmbed(msg.ChannelID, info)
+	return nil
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/route"
+)
+
+// promoteStandbys takes users off standby for the routes until each route has as many users
+// as the map expects. Users that went on standby first are promoted first. It should be run
+// in the same transaction that removed users from the routes. The promoted routes are returned
+func promoteStandbys(ctx context.Context, rs *route.Service, m route.Map, channelID string, refs []RouteRef, now time.Time) ([]route.Route, error) {
+	routes, err := getChannelRoutes(ctx, rs, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := route.IndexRoutes(routes)
+	promoted := []route.Route{}
+	seen := map[RouteRef]struct{}{}
+	for _, ref := range refs {
+		if _, ok := seen[ref]; ok {
+			continue
+		}
+		seen[ref] = struct{}{}
+
+		primary, standby, _ := route.SplitRoutes(idx[route.RouteKey(ref.Section, ref.Path)])
+		for open := m.PathCapacity() - len(primary); open > 0 && len(standby) > 0; open-- {
+			r := standby[0]
+			standby = standby[1:]
+
+			r.Standby = false
+			r.UpdatedAt = now
+			err = rs.InsertRoute(ctx, r)
+			if err != nil {
+				return nil, SystemError{
+					error:   err,
+					Message: "Something went wrong promoting a standby",
+					Stack:   debug.Stack(),
+				}
+			}
+
+			promoted = append(promoted, r)
+		}
+	}
+
+	return promoted, nil
+}
+
+// notifyPromoted lets the promoted users know they are no longer on standby and returns a
+// note for the route board
+func notifyPromoted(sess *discordgo.Session, channelID string, promoted []route.Route) string {
+	note := ""
+	for _, r := range promoted {
+		ref := RouteRef{Section: r.Section, Path: r.Path}
+		sess.ChannelMessageSend(channelID, fmt.Sprintf("<@!%s> you have been promoted from standby and are now on **%s**", r.UserID, ref))
+		note += fmt.Sprintf("\nPromoted <@!%s> from standby on **%s**", r.UserID, ref)
+	}
+
+	return note
+}
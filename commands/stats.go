@@ -0,0 +1,304 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/audit"
+	"github.com/duke605/NickFury/route"
+	"github.com/spf13/viper"
+)
+
+const (
+	// maxStatsDays is the largest window in days that stats can be calculated for
+	maxStatsDays = 365
+
+	// maxLeaderboard is the most members shown on the leaderboard
+	maxLeaderboard = 10
+
+	// maxTopPaths is the most paths shown as a member's most used paths
+	maxTopPaths = 3
+)
+
+// memberStats is how a member has taken part in raids
+type memberStats struct {
+	UserID   string
+	Raids    int
+	Sections map[int]int
+	Paths    map[RouteRef]int
+	Linked   map[string]struct{}
+	Kept     map[string]struct{}
+}
+
+// completion returns the percentage of the links the member made that were still in place
+// when their raid was archived and false if the member has not linked to anything
+func (ms *memberStats) completion() (int, bool) {
+	if len(ms.Linked) == 0 {
+		return 0, false
+	}
+
+	return len(ms.Kept) * 100 / len(ms.Linked), true
+}
+
+// pathsTaken returns the total number of paths the member took in raids
+func (ms *memberStats) pathsTaken() int {
+	n := 0
+	for _, c := range ms.Paths {
+		n += c
+	}
+
+	return n
+}
+
+type stats struct {
+	User Mention `arg:"" optional:"" name:"user" help:"The user to show stats for. Leave out to show your own"`
+	Days int     `name:"days" default:"30" help:"The number of days to look back over"`
+}
+
+func (s *stats) AfterApply() error {
+	return validateDays(s.Days, "stats")
+}
+
+func (s *stats) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, ar *audit.Repository) error {
+	userID := msg.Author.ID
+	if s.User != "" {
+		userID = string(s.User)
+	}
+
+	all, err := collectStats(rs, ar, msg.GuildID, time.Now().AddDate(0, 0, -s.Days))
+	if err != nil {
+		return err
+	}
+
+	ms, ok := all[userID]
+	if !ok || (ms.Raids == 0 && len(ms.Linked) == 0) {
+		return Warning{
+			Message: fmt.Sprintf("<@!%s> has not taken part in any raids in the last %d day(s)", userID, s.Days),
+		}
+	}
+
+	// Listing the paths taken in each section
+	sections := []int{}
+	for section := range ms.Sections {
+		sections = append(sections, section)
+	}
+	sort.Ints(sections)
+	perSection := make([]string, len(sections))
+	for i, section := range sections {
+		perSection[i] = fmt.Sprintf("Section %d: **%d**", section, ms.Sections[section])
+	}
+
+	// Finding the paths taken most often
+	paths := []RouteRef{}
+	for ref := range ms.Paths {
+		paths = append(paths, ref)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if ms.Paths[paths[i]] != ms.Paths[paths[j]] {
+			return ms.Paths[paths[i]] > ms.Paths[paths[j]]
+		}
+
+		return paths[i].String() < paths[j].String()
+	})
+	if len(paths) > maxTopPaths {
+		paths = paths[:maxTopPaths]
+	}
+	top := make([]string, len(paths))
+	for i, ref := range paths {
+		top[i] = fmt.Sprintf("**%s** ×%d", ref, ms.Paths[ref])
+	}
+
+	completion := "n/a"
+	if rate, ok := ms.completion(); ok {
+		completion = fmt.Sprintf("%d%%", rate)
+	}
+
+	info := newInfoEmbed()
+	info.Title = fmt.Sprintf("Stats for the last %d day(s)", s.Days)
+	info.Description = fmt.Sprintf("<@!%s>", userID)
+	info.Fields = []*discordgo.MessageEmbedField{
+		{Name: "Raids", Value: fmt.Sprint(ms.Raids), Inline: true},
+		{Name: "Completion rate", Value: completion, Inline: true},
+		{Name: "Paths per section", Value: orNone(strings.Join(perSection, "\n"))},
+		{Name: "Most used paths", Value: orNone(strings.Join(top, ", "))},
+	}
+	sess.ChannelMessageSendEmbed(msg.ChannelID, info)
+	return nil
+}
+
+type leaderboard struct {
+	Days int `name:"days" default:"30" help:"The number of days to look back over"`
+}
+
+func (l *leaderboard) AfterApply() error {
+	return validateDays(l.Days, "leaderboard")
+}
+
+func (l *leaderboard) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, ar *audit.Repository) error {
+	all, err := collectStats(rs, ar, msg.GuildID, time.Now().AddDate(0, 0, -l.Days))
+	if err != nil {
+		return err
+	}
+
+	ranked := []*memberStats{}
+	for _, ms := range all {
+		if ms.Raids > 0 {
+			ranked = append(ranked, ms)
+		}
+	}
+	if len(ranked) == 0 {
+		return Warning{
+			Message: fmt.Sprintf("Nobody has taken part in a raid in the last %d day(s)", l.Days),
+		}
+	}
+
+	// Ranking members by the raids they took part in and then by the paths they took
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Raids != ranked[j].Raids {
+			return ranked[i].Raids > ranked[j].Raids
+		}
+		if ranked[i].pathsTaken() != ranked[j].pathsTaken() {
+			return ranked[i].pathsTaken() > ranked[j].pathsTaken()
+		}
+
+		return ranked[i].UserID < ranked[j].UserID
+	})
+	if len(ranked) > maxLeaderboard {
+		ranked = ranked[:maxLeaderboard]
+	}
+
+	lines := make([]string, len(ranked))
+	for i, ms := range ranked {
+		lines[i] = fmt.Sprintf("**%d.** <@!%s> %d raid(s), %d path(s)", i+1, ms.UserID, ms.Raids, ms.pathsTaken())
+		if rate, ok := ms.completion(); ok {
+			lines[i] += fmt.Sprintf(", %d%% completion", rate)
+		}
+	}
+
+	info := newInfoEmbed()
+	info.Title = fmt.Sprintf("Leaderboard for the last %d day(s)", l.Days)
+	info.Description = strings.Join(lines, "\n")
+	sess.ChannelMessageSendEmbed(msg.ChannelID, info)
+	return nil
+}
+
+// collectStats calculates the stats of every member in the guild from the raids archived
+// and the links made since the time
+func collectStats(rs *route.Service, ar *audit.Repository, guildID string, since time.Time) (map[string]*memberStats, error) {
+	ctx := context.Background()
+	archives, err := rs.GetArchivesSince(ctx, since)
+	if err != nil {
+		return nil, SystemError{
+			error:   err,
+			Message: "Something went wrong getting archived raids",
+			Stack:   debug.Stack(),
+		}
+	}
+
+	entries, err := ar.GetEntriesSince(ctx, since)
+	if err != nil {
+		return nil, SystemError{
+			error:   err,
+			Message: "Something went wrong getting the route history",
+			Stack:   debug.Stack(),
+		}
+	}
+
+	all := map[string]*memberStats{}
+	get := func(userID string) *memberStats {
+		ms, ok := all[userID]
+		if !ok {
+			ms = &memberStats{
+				UserID:   userID,
+				Sections: map[int]int{},
+				Paths:    map[RouteRef]int{},
+				Linked:   map[string]struct{}{},
+				Kept:     map[string]struct{}{},
+			}
+			all[userID] = ms
+		}
+
+		return ms
+	}
+
+	// Counting the routes members linked to
+	for _, e := range entries {
+		if e.GuildID != guildID {
+			continue
+		}
+
+		for _, r := range e.After {
+			if !r.Pending && !r.Standby && !r.Reserved {
+				get(r.UserID).Linked[string(r.GetID())] = struct{}{}
+			}
+		}
+	}
+
+	// Counting the routes members were on when raids were archived. Links only count as kept
+	// when they were made in the same window so the completion rate compares like with like
+	for _, a := range archives {
+		if a.GuildID != guildID {
+			continue
+		}
+
+		primary, _, _ := route.SplitRoutes(a.Routes)
+		raided := map[string]struct{}{}
+		for _, r := range primary {
+			if r.Reserved {
+				continue
+			}
+
+			ms := get(r.UserID)
+			ms.Sections[r.Section]++
+			ms.Paths[RouteRef{Section: r.Section, Path: r.Path}]++
+			if _, ok := ms.Linked[string(r.GetID())]; ok {
+				ms.Kept[string(r.GetID())] = struct{}{}
+			}
+			raided[r.UserID] = struct{}{}
+		}
+
+		for userID := range raided {
+			get(userID).Raids++
+		}
+	}
+
+	return all, nil
+}
+
+// validateDays checks that the number of days to look back over is in the acceptable range.
+// Stats can not go back further than the audit log is kept since links are counted from it
+func validateDays(days int, cmd string) error {
+	cmdPrefix := viper.GetString("COMMAND_PREFIX")
+
+	limit := int(viper.GetDuration("AUDIT_RETENTION") / (24 * time.Hour))
+	if limit > maxStatsDays {
+		limit = maxStatsDays
+	} else if limit < 1 {
+		limit = 1
+	}
+
+	if days < 1 || days > limit {
+		return UsageError{
+			Param:    "days",
+			Message:  fmt.Sprintf("Must be between 1 and %d (inclusive)", limit),
+			Provided: days,
+			Footer:   fmt.Sprintf("Type %s%s --help for command usage", cmdPrefix, cmd),
+		}
+	}
+
+	return nil
+}
+
+// orNone returns the text or a placeholder when the text is empty
+func orNone(text string) string {
+	if text == "" {
+		return "*none*"
+	}
+
+	return text
+}
@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/boltdb/bolt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/route"
+)
+
+type swap struct {
+	UserA  Mention  `arg:"" name:"user-a" help:"The first user to swap"`
+	RouteA RouteRef `arg:"" name:"route-a" help:"The route the first user is linked to (eg. 1A)"`
+	UserB  Mention  `arg:"" name:"user-b" help:"The second user to swap"`
+	RouteB RouteRef `arg:"" name:"route-b" help:"The route the second user is linked to (eg. 2C)"`
+}
+
+func (s *swap) restricted() bool { return true }
+
+func (s *swap) audited() bool { return true }
+
+func (s *swap) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, m route.Map, bu *BoardUpdater) error {
+	var routes []route.Route
+	var err error
+
+	ctx := context.Background()
+	err = rs.InTransaction(ctx, true, func(ctx context.Context, _ *bolt.Tx) error {
+
+		// Getting the already selected routes for the channel
+		routes, err = getChannelRoutes(ctx, rs, msg.ChannelID)
+		if err != nil {
+			return err
+		}
+
+		// Finding the routes being swapped
+		a, b := -1, -1
+		for i, r := range routes {
+			switch {
+			case r.UserID == string(s.UserA) && r.Section == s.RouteA.Section && r.Path == s.RouteA.Path:
+				a = i
+			case r.UserID == string(s.UserB) && r.Section == s.RouteB.Section && r.Path == s.RouteB.Path:
+				b = i
+			case r.UserID == string(s.UserA) && r.Section == s.RouteB.Section && r.Path == s.RouteB.Path,
+				r.UserID == string(s.UserB) && r.Section == s.RouteA.Section && r.Path == s.RouteA.Path:
+				return Warning{
+					Message: fmt.Sprintf("<@!%s> is already linked to both routes", r.UserID),
+				}
+			}
+		}
+		if a == -1 {
+			return Warning{
+				Message: fmt.Sprintf("<@!%s> is not linked to %s", s.UserA, s.RouteA),
+			}
+		}
+		if b == -1 {
+			return Warning{
+				Message: fmt.Sprintf("<@!%s> is not linked to %s", s.UserB, s.RouteB),
+			}
+		}
+
+		// Making sure users arriving on a route are not taking a place reserved for someone else
+		now := messageTime(msg)
+		idx := route.IndexRoutes(routes)
+		for _, arrival := range [][2]int{{a, b}, {b, a}} {
+			arriving, leaving := routes[arrival[0]], routes[arrival[1]]
+			if arriving.Standby || arriving.Pending {
+				continue
+			}
+
+			others := []route.Route{}
+			for _, r := range idx[route.RouteKey(leaving.Section, leaving.Path)] {
+				if string(r.GetID()) != string(leaving.GetID()) {
+					others = append(others, r)
+				}
+			}
+			if reservedForOthers(others, arriving.UserID, m.PathCapacity(), now) {
+				return Warning{
+					Message: fmt.Sprintf("%s is reserved for someone else", RouteRef{Section: leaving.Section, Path: leaving.Path}),
+				}
+			}
+		}
+
+		// Replacing the old routes with the swapped ones
+		for _, i := range []int{a, b} {
+			err = rs.DeleteRoute(ctx, routes[i])
+			if err != nil {
+				return SystemError{
+					error:   err,
+					Message: "Something went wrong unlinking the users from their current routes",
+					Stack:   debug.Stack(),
+				}
+			}
+		}
+
+		routes[a].Section, routes[b].Section = routes[b].Section, routes[a].Section
+		routes[a].Path, routes[b].Path = routes[b].Path, routes[a].Path
+		routes[a].UpdatedAt = now
+		routes[b].UpdatedAt = routes[a].UpdatedAt
+		for _, i := range []int{a, b} {
+			err = rs.InsertRoute(ctx, routes[i])
+			if err != nil {
+				return SystemError{
+					error:   err,
+					Message: "Something went wrong linking the users to their new routes",
+					Stack:   debug.Stack(),
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	content := fmt.Sprintf("Swapped <@!%s> (**%s**) with <@!%s> (**%s**)", s.UserA, s.RouteA, s.UserB, s.RouteB)
+	bu.Request(msg.ChannelID, content)
+	return nil
+}
@@ -2,10 +2,23 @@ package commands
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
 	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"github.com/duke605/NickFury/route"
+	"github.com/spf13/viper"
 )
 
 var mentionPattern = regexp.MustCompile(`(?:^<@\!?(\d+)>$|^(\d+)$)`)
+var channelMentionPattern = regexp.MustCompile(`(?:^<#(\d+)>$|^(\d+)$)`)
+var routeRefPattern = regexp.MustCompile(`^(\d+)([A-Za-z])$`)
+var routeSelectorPattern = regexp.MustCompile(`^(\d+)(?:(\*)|([A-Za-z])(?:-(\d+)?([A-Za-z]))?)?$`)
+var numberPattern = regexp.MustCompile(`^\d+$`)
+var letterPattern = regexp.MustCompile(`^[A-Za-z]$`)
 
 // Mention is a argument type that can either be a mention or an id
 type Mention string
@@ -28,3 +41,224 @@ func (m *Mention) UnmarshalText(b []byte) error {
 	*m = Mention(groups[2])
 	return nil
 }
+
+// ChannelMention is a argument type that can either be a channel mention or an id
+type ChannelMention string
+
+// UnmarshalText ...
+func (c *ChannelMention) UnmarshalText(b []byte) error {
+	groups := channelMentionPattern.FindStringSubmatch(string(b))
+	if groups == nil {
+		return errors.New("Must be a channel mention (#channel) or a raw ID")
+	}
+
+	*c = ChannelMention(groups[1] + groups[2])
+	return nil
+}
+
+// RouteRef is a argument type that references a section and path in the
+// compact form of 1A
+type RouteRef struct {
+	Section int
+	Path    string
+}
+
+// UnmarshalText ...
+func (r *RouteRef) UnmarshalText(b []byte) error {
+	groups := routeRefPattern.FindStringSubmatch(string(b))
+	if groups == nil {
+		return errors.New("Must be a section followed by a path (eg. 1A)")
+	}
+
+	section, err := strconv.Atoi(groups[1])
+	if err != nil {
+		return errors.New("Section must be a number")
+	}
+
+	r.Section = section
+	r.Path = strings.ToUpper(groups[2])
+	return nil
+}
+
+// Decode reads the route from the next argument. A section and path provided as separate
+// arguments (1 A) are also accepted
+func (r *RouteRef) Decode(ctx *kong.DecodeContext) error {
+	var value string
+	if err := ctx.Scan.PopValueInto("route", &value); err != nil {
+		return err
+	}
+
+	// Joining the section and path when they were provided as seperate arguments. Only a
+	// single letter is joined, the same as RouteSelectorsMapper, so 1 2C is not read as 12C
+	if next := ctx.Scan.Peek(); numberPattern.MatchString(value) && next.IsValue() && letterPattern.MatchString(next.String()) {
+		value += ctx.Scan.Pop().String()
+	}
+
+	return r.UnmarshalText([]byte(value))
+}
+
+// AfterApply validates the route against the map for the channel
+func (r *RouteRef) AfterApply(kctx *kong.Context, trace *kong.Path, m route.Map) error {
+	return validateRoute(m, r.Section, r.Path, valueName(trace), kctx.Selected().Name)
+}
+
+func (r RouteRef) String() string {
+	return strconv.Itoa(r.Section) + r.Path
+}
+
+// RouteSelector selects a range of paths in a section. A selector without
+// paths selects every path in the section. Wildcard is true when every path was
+// selected with a * instead of leaving the path out
+type RouteSelector struct {
+	Section  int
+	From     string
+	To       string
+	Wildcard bool
+}
+
+// RouteSelectors is a argument type that accepts routes in compact notation. Routes
+// can be listed (1A 2C), be a range in a section (1A-1C) or be every path in a section (2*)
+type RouteSelectors []RouteSelector
+
+// RouteSelectorsMapper decodes every remaining value argument into RouteSelectors. Sections
+// and paths given as separate arguments (1 A) are joined together when the path is a
+// single letter
+func RouteSelectorsMapper(ctx *kong.DecodeContext, target reflect.Value) error {
+	tokens := ctx.Scan.PopWhile(func(t kong.Token) bool { return t.IsValue() })
+
+	// Joining sections and paths that were provided as separate arguments
+	args := []string{}
+	for _, t := range tokens {
+		arg := t.String()
+		if n := len(args); n > 0 && numberPattern.MatchString(args[n-1]) && letterPattern.MatchString(arg) {
+			args[n-1] += arg
+			continue
+		}
+
+		args = append(args, arg)
+	}
+
+	selectors := RouteSelectors{}
+	for _, arg := range args {
+		groups := routeSelectorPattern.FindStringSubmatch(arg)
+		if groups == nil {
+			return fmt.Errorf(`Invalid route "%s". Must be like 1A, 1A-1C or 2*`, arg)
+		}
+
+		section, _ := strconv.Atoi(groups[1])
+		if groups[4] != "" && groups[4] != groups[1] {
+			return fmt.Errorf(`Invalid route "%s". Ranges must be in a single section`, arg)
+		}
+
+		s := RouteSelector{
+			Section:  section,
+			From:     strings.ToUpper(groups[3]),
+			To:       strings.ToUpper(groups[5]),
+			Wildcard: groups[2] != "",
+		}
+		if s.To == "" {
+			s.To = s.From
+		}
+		selectors = append(selectors, s)
+	}
+
+	target.Set(reflect.ValueOf(selectors))
+	return nil
+}
+
+// AfterApply validates the selectors against the map for the channel
+func (rs RouteSelectors) AfterApply(kctx *kong.Context, trace *kong.Path, m route.Map) error {
+	cmdPrefix := viper.GetString("COMMAND_PREFIX")
+	param := valueName(trace)
+	cmd := kctx.Selected().Name
+
+	for _, s := range rs {
+
+		// Only linking to every path in a section when it was asked for with a * so a
+		// forgotten path does not link to the whole section
+		if s.From == "" && !s.Wildcard && cmd == "link" {
+			return UsageError{
+				Param:    param,
+				Message:  fmt.Sprintf("Must include a path (eg. %dA) or * to link to every path in the section (eg. %d*)", s.Section, s.Section),
+				Provided: s.Section,
+				Footer:   fmt.Sprintf("Type %s%s --help for command usage", cmdPrefix, cmd),
+			}
+		}
+
+		// Selecting every path in the section only requires the section to be valid
+		if s.From == "" {
+			if err := validateRoute(m, s.Section, "A", param, cmd); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := validateRoute(m, s.Section, s.From, param, cmd); err != nil {
+			return err
+		}
+		if err := validateRoute(m, s.Section, s.To, param, cmd); err != nil {
+			return err
+		}
+		if s.From > s.To {
+			return UsageError{
+				Param:    param,
+				Message:  fmt.Sprintf("Range %d%s-%d%s must go from the lower path to the higher path", s.Section, s.From, s.Section, s.To),
+				Provided: s,
+			}
+		}
+	}
+
+	return nil
+}
+
+// Refs returns every route the selectors select on the map. Routes selected more than
+// once are only returned once. The selectors are expected to be valid for the map
+func (rs RouteSelectors) Refs(m route.Map) []RouteRef {
+	refs := []RouteRef{}
+	seen := map[RouteRef]struct{}{}
+
+	for _, s := range rs {
+		from, to := s.From, s.To
+
+		// Selecting every path in the section
+		if from == "" {
+			paths := m.Paths(s.Section)
+			from, to = paths[0], paths[len(paths)-1]
+		}
+
+		for p := from[0]; p <= to[0]; p++ {
+			ref := RouteRef{Section: s.Section, Path: string(p)}
+			if _, ok := seen[ref]; ok {
+				continue
+			}
+
+			seen[ref] = struct{}{}
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs
+}
+
+// valueName returns the name of the argument or flag the value in the path is for
+func valueName(p *kong.Path) string {
+	switch {
+	case p.Positional != nil:
+		return p.Positional.Name
+	case p.Flag != nil:
+		return p.Flag.Name
+	default:
+		return ""
+	}
+}
+
+// joinRouteRefs formats the routes as a bold comma seperated list
+func joinRouteRefs(refs []RouteRef) string {
+	strs := make([]string, len(refs))
+	for i, r := range refs {
+		strs[i] = fmt.Sprintf("**%s**", r)
+	}
+
+	return strings.Join(strs, ", ")
+}
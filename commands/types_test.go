@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+func TestRouteRefDecode(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    []RouteRef
+		wantErr bool
+	}{
+		{args: []string{"1A", "2C"}, want: []RouteRef{{1, "A"}, {2, "C"}}},
+		{args: []string{"1", "a", "2", "C"}, want: []RouteRef{{1, "A"}, {2, "C"}}},
+		{args: []string{"10", "B"}, want: []RouteRef{{10, "B"}}},
+
+		// Only single letters are joined so a section missing its path is not read as 12C
+		{args: []string{"1", "2C"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		ctx := &kong.DecodeContext{Scan: kong.Scan(tt.args...)}
+		got := []RouteRef{}
+		for ctx.Scan.Peek().IsValue() {
+			r := RouteRef{}
+			if err := r.Decode(ctx); err != nil {
+				got = nil
+				break
+			}
+			got = append(got, r)
+		}
+
+		if tt.wantErr {
+			if got != nil {
+				t.Errorf("decoding %q got %v, want an error", tt.args, got)
+			}
+			continue
+		}
+
+		if len(got) != len(tt.want) {
+			t.Fatalf("decoding %q got %v, want %v", tt.args, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("decoding %q got %v, want %v", tt.args, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/bwmarrin/discordgo"
+	"github.com/duke605/NickFury/audit"
+	"github.com/duke605/NickFury/route"
+	"github.com/spf13/viper"
+)
+
+// maxUndo is the most changes that can be undone at once
+const maxUndo = 10
+
+type undo struct {
+	Count int `arg:"" optional:"" name:"count" default:"1" help:"The number of changes to undo"`
+
+	reverted []uint64
+}
+
+func (u *undo) AfterApply() error {
+	cmdPrefix := viper.GetString("COMMAND_PREFIX")
+
+	// Checking that the count is in the acceptable range
+	if u.Count < 1 || u.Count > maxUndo {
+		return UsageError{
+			Param:    "count",
+			Message:  fmt.Sprintf("Must be between 1 and %d (inclusive)", maxUndo),
+			Provided: u.Count,
+			Footer:   fmt.Sprintf("Type %sundo --help for command usage", cmdPrefix),
+		}
+	}
+
+	return nil
+}
+
+func (u *undo) audited() bool { return true }
+
+// audit records which entries were undone so they can not be undone again
+func (u *undo) audit(e *audit.Entry) {
+	e.Reverts = u.reverted
+}
+
+func (u *undo) Run(sess *discordgo.Session, msg *discordgo.MessageCreate, rs *route.Service, ar *audit.Repository, bu *BoardUpdater) error {
+	var entries []audit.Entry
+
+	ctx := context.Background()
+	err := rs.InTransaction(ctx, true, func(ctx context.Context, _ *bolt.Tx) error {
+		var err error
+		entries, err = ar.GetUndoable(ctx, msg.ChannelID, u.Count)
+		if err != nil {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong getting the history for this channel",
+				Stack:   debug.Stack(),
+			}
+		}
+		if len(entries) == 0 {
+			return Warning{
+				Message: "There are no changes to undo in this channel",
+			}
+		}
+
+		current, err := takeSnapshot(ctx, rs, msg.ChannelID)
+		if err != nil {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong getting the routes for this channel",
+				Stack:   debug.Stack(),
+			}
+		}
+
+		// Only officers can undo changes made by someone else or undo while the routes are locked
+		officer := current.Map != nil && current.Map.Locked
+		for _, e := range entries {
+			officer = officer || e.ActorID != msg.Author.ID
+		}
+		if officer {
+			if err := checkOfficer(sess, msg); err != nil {
+				return err
+			}
+		}
+
+		// Undoing the newest changes first
+		target := current
+		for _, e := range entries {
+			target, err = e.Revert(target)
+			if errors.Is(err, audit.ErrConflict) {
+				return Warning{
+					Message: fmt.Sprintf("Can not undo `#%d` because what it changed has been changed since", e.ID),
+				}
+			}
+		}
+
+		err = restoreSnapshot(ctx, rs, msg.ChannelID, current, target)
+		if err != nil {
+			return SystemError{
+				error:   err,
+				Message: "Something went wrong undoing the changes",
+				Stack:   debug.Stack(),
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	undone := make([]string, len(entries))
+	for i, e := range entries {
+		u.reverted = append(u.reverted, e.ID)
+		undone[i] = fmt.Sprintf("`#%d` %s", e.ID, e.Action)
+	}
+
+	bu.Request(msg.ChannelID, fmt.Sprintf("<@!%s> undid %s", msg.Author.ID, strings.Join(undone, ", ")))
+	return nil
+}
+
+// restoreSnapshot changes the channel's routes and map from the current snapshot to the target
+func restoreSnapshot(ctx context.Context, rs *route.Service, channelID string, current, target audit.Snapshot) error {
+	keep := map[string]struct{}{}
+	for _, r := range target.Routes {
+		keep[string(r.GetID())] = struct{}{}
+		if err := rs.InsertRoute(ctx, r); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range current.Routes {
+		if _, ok := keep[string(r.GetID())]; ok {
+			continue
+		}
+		if err := rs.DeleteRoute(ctx, r); err != nil {
+			return err
+		}
+	}
+
+	if target.Map == nil {
+		return rs.DeleteMap(ctx, channelID)
+	}
+
+	return rs.InsertMap(ctx, *target.Map)
+}
@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and schedules functions so the board updater can be driven by a
+// fake clock
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func())
+}
+
+type realClock struct{}
+
+// RealClock is a Clock backed by the time package
+var RealClock Clock = realClock{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) {
+	time.AfterFunc(d, f)
+}
+
+// BoardRenderer renders the current route board for the channel along with the notes about
+// what changed since the last render. repost is true when the board should be posted as new
+// messages instead of editing the existing board
+type BoardRenderer func(channelID string, notes []string, repost bool)
+
+// BoardUpdater coalesces requests to update a channel's route board so the board is rendered
+// at most once per interval. The renderer is expected to render the latest state of the
+// channel so the last request is always reflected on the board
+type BoardUpdater struct {
+	mu       sync.Mutex
+	clock    Clock
+	interval time.Duration
+	render   BoardRenderer
+	channels map[string]*pendingBoard
+}
+
+type pendingBoard struct {
+	renderMu  sync.Mutex
+	last      time.Time
+	notes     []string
+	repost    bool
+	scheduled bool
+}
+
+// NewBoardUpdater creates a new BoardUpdater
+func NewBoardUpdater(clock Clock, interval time.Duration, render BoardRenderer) *BoardUpdater {
+	return &BoardUpdater{
+		clock:    clock,
+		interval: interval,
+		render:   render,
+		channels: map[string]*pendingBoard{},
+	}
+}
+
+// Request schedules the channel's board to be rendered. The note is shown with the board
+// when it is not empty
+func (bu *BoardUpdater) Request(channelID, note string) {
+	bu.request(channelID, note, false)
+}
+
+// Repost schedules the channel's board to be posted as a new board. The note is shown with
+// the board when it is not empty
+func (bu *BoardUpdater) Repost(channelID, note string) {
+	bu.request(channelID, note, true)
+}
+
+// request schedules the channel's board to be rendered and reposted if asked for
+func (bu *BoardUpdater) request(channelID, note string, repost bool) {
+	bu.mu.Lock()
+	defer bu.mu.Unlock()
+
+	p, ok := bu.channels[channelID]
+	if !ok {
+		p = &pendingBoard{}
+		bu.channels[channelID] = p
+	}
+
+	if note != "" {
+		p.notes = append(p.notes, note)
+	}
+	p.repost = p.repost || repost
+
+	// A render is already scheduled and will pick up this request
+	if p.scheduled {
+		return
+	}
+
+	wait := bu.interval - bu.clock.Now().Sub(p.last)
+	if wait < 0 {
+		wait = 0
+	}
+	p.scheduled = true
+	bu.clock.AfterFunc(wait, func() {
+		bu.flush(channelID, p)
+	})
+}
+
+// flush renders the channel's board with the notes that were collected since the last render
+func (bu *BoardUpdater) flush(channelID string, p *pendingBoard) {
+
+	// Only one render can happen at a time for a channel so an older state can not
+	// be rendered after a newer one
+	p.renderMu.Lock()
+	defer p.renderMu.Unlock()
+
+	bu.mu.Lock()
+	notes, repost := p.notes, p.repost
+	p.notes = nil
+	p.repost = false
+	p.scheduled = false
+	p.last = bu.clock.Now()
+	bu.mu.Unlock()
+
+	bu.render(channelID, notes, repost)
+}
@@ -0,0 +1,197 @@
+package commands
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// manualClock is a Clock that only moves when it is advanced. Functions scheduled with
+// AfterFunc run on the goroutine that advances the clock past their time
+type manualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []manualTimer
+}
+
+type manualTimer struct {
+	at time.Time
+	f  func()
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{now: time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *manualClock) AfterFunc(d time.Duration, f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.timers = append(c.timers, manualTimer{at: c.now.Add(d), f: f})
+}
+
+// Advance moves the clock forward and runs the functions that are due. The functions are
+// run without holding the clock's lock so they can use the clock
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	due, waiting := []manualTimer{}, []manualTimer{}
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			waiting = append(waiting, t)
+		} else {
+			due = append(due, t)
+		}
+	}
+	c.timers = waiting
+	c.mu.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].at.Before(due[j].at)
+	})
+	for _, t := range due {
+		t.f()
+	}
+}
+
+// render is a single call to the board renderer
+type render struct {
+	channelID string
+	notes     []string
+	repost    bool
+}
+
+// renderRecorder records the calls made to the board renderer
+type renderRecorder struct {
+	mu      sync.Mutex
+	renders []render
+}
+
+func (rr *renderRecorder) render(channelID string, notes []string, repost bool) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	rr.renders = append(rr.renders, render{channelID: channelID, notes: notes, repost: repost})
+}
+
+func (rr *renderRecorder) get() []render {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	return append([]render{}, rr.renders...)
+}
+
+func TestBoardUpdaterRendersOncePerInterval(t *testing.T) {
+	clock, rr := newManualClock(), &renderRecorder{}
+	bu := NewBoardUpdater(clock, time.Second, rr.render)
+
+	// The first burst is rendered straight away as nothing was rendered before
+	for i := 0; i < 5; i++ {
+		bu.Request("1", "")
+	}
+	clock.Advance(0)
+	if n := len(rr.get()); n != 1 {
+		t.Fatalf("got %d renders after the first burst, want 1", n)
+	}
+
+	// The second burst has to wait for the interval to pass
+	for i := 0; i < 5; i++ {
+		bu.Request("1", "")
+	}
+	clock.Advance(time.Second - time.Nanosecond)
+	if n := len(rr.get()); n != 1 {
+		t.Fatalf("got %d renders before the interval passed, want 1", n)
+	}
+	clock.Advance(time.Nanosecond)
+	if n := len(rr.get()); n != 2 {
+		t.Fatalf("got %d renders after the interval passed, want 2", n)
+	}
+
+	// Nothing is rendered when nothing was requested
+	clock.Advance(3 * time.Second)
+	if n := len(rr.get()); n != 2 {
+		t.Fatalf("got %d renders without a request, want 2", n)
+	}
+}
+
+func TestBoardUpdaterCoalescesNotes(t *testing.T) {
+	clock, rr := newManualClock(), &renderRecorder{}
+	bu := NewBoardUpdater(clock, time.Second, rr.render)
+
+	bu.Request("1", "a")
+	bu.Request("2", "x")
+	bu.Request("1", "b")
+	bu.Request("1", "")
+	bu.Request("1", "c")
+	clock.Advance(0)
+
+	bu.Request("1", "d")
+	bu.Repost("1", "e")
+	bu.Request("1", "f")
+	clock.Advance(time.Second)
+
+	want := []render{
+		{channelID: "1", notes: []string{"a", "b", "c"}},
+		{channelID: "2", notes: []string{"x"}},
+		{channelID: "1", notes: []string{"d", "e", "f"}, repost: true},
+	}
+	if got := rr.get(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got renders %+v, want %+v", got, want)
+	}
+}
+
+func TestBoardUpdaterRequestDuringFlush(t *testing.T) {
+	clock, rr := newManualClock(), &renderRecorder{}
+	started, release := make(chan struct{}), make(chan struct{})
+	first := true
+	bu := NewBoardUpdater(clock, time.Second, func(channelID string, notes []string, repost bool) {
+		rr.render(channelID, notes, repost)
+
+		// Holding the first render until the test has made another request
+		if first {
+			first = false
+			close(started)
+			<-release
+		}
+	})
+
+	bu.Request("1", "a")
+	done := make(chan struct{})
+	go func() {
+		clock.Advance(0)
+		close(done)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the first render")
+	}
+
+	bu.Request("1", "b")
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the first render to finish")
+	}
+
+	clock.Advance(time.Second)
+
+	want := []render{
+		{channelID: "1", notes: []string{"a"}},
+		{channelID: "1", notes: []string{"b"}},
+	}
+	if got := rr.get(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got renders %+v, want %+v", got, want)
+	}
+}
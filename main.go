@@ -1,15 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
+	"reflect"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/boltdb/bolt"
+	"github.com/duke605/NickFury/audit"
 	"github.com/duke605/NickFury/commands"
 	"github.com/duke605/NickFury/route"
 	"github.com/google/shlex"
@@ -25,11 +28,21 @@ var (
 	db  *bolt.DB
 
 	routeService *route.Service
+	auditRepo    *audit.Repository
+	boardUpdater *commands.BoardUpdater
+	memberCache  *commands.MemberCache
+	rateLimiter  = commands.NewRateLimiter(5, 10*time.Second)
+	channelLocks = commands.NewKeyedMutex()
 )
 
 func init() {
 	var err error
 	viper.AutomaticEnv()
+	viper.SetDefault("BOARD_UPDATE_INTERVAL", 2*time.Second)
+	viper.SetDefault("MEMBER_CACHE_TTL", 10*time.Minute)
+	viper.SetDefault("TIMEZONE", "UTC")
+	viper.SetDefault("AUDIT_RETENTION", 30*24*time.Hour)
+	viper.SetDefault("MAX_MEMBER_PATHS", 0)
 
 	// Initializing bot
 	bot, err = dg.New("Bot " + viper.GetString("DISCORD_TOKEN"))
@@ -42,9 +55,10 @@ func init() {
 	bot.State.TrackVoice = false
 	bot.State.TrackChannels = false
 	bot.SyncEvents = false
-	bot.Identify.Intents = dg.MakeIntent(dg.IntentsGuildMessages)
+	bot.Identify.Intents = dg.MakeIntent(dg.IntentsGuildMessages | dg.IntentsGuildMessageReactions)
 
 	bot.AddHandler(onMessage)
+	bot.AddHandler(onReactionAdd)
 	bot.AddHandlerOnce(onReady)
 
 	// Creating repos
@@ -53,9 +67,16 @@ func init() {
 		panic(err)
 	}
 	routeRepo := route.NewRepo(db)
+	auditRepo = audit.NewRepo(db)
 
 	// Creating services
 	routeService = route.NewService(routeRepo)
+	memberCache = commands.NewMemberCache(commands.RealClock, viper.GetDuration("MEMBER_CACHE_TTL"))
+	boardUpdater = commands.NewBoardUpdater(
+		commands.RealClock,
+		viper.GetDuration("BOARD_UPDATE_INTERVAL"),
+		commands.RenderRouteBoard(bot, routeService, memberCache),
+	)
 }
 
 func main() {
@@ -63,6 +84,22 @@ func main() {
 		panic(err)
 	}
 
+	// Removing reservations that were not claimed in time and old audit log entries,
+	// alerting officers about raids that are ending with paths uncovered and archiving
+	// raids that have ended
+	go func() {
+		for now := range time.Tick(time.Minute) {
+			commands.ExpireReservations(bot, routeService, auditRepo, boardUpdater, channelLocks, now)
+			commands.SendDeadlineAlerts(bot, routeService, now)
+			commands.EndRaids(bot, routeService, auditRepo, boardUpdater, channelLocks, now)
+
+			err := auditRepo.DeleteBefore(context.Background(), now.Add(-viper.GetDuration("AUDIT_RETENTION")))
+			if err != nil {
+				fmt.Println("Error occured removing old audit log entries: ", err)
+			}
+		}
+	}()
+
 	// Waiting for kill command
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt, os.Kill)
@@ -109,22 +146,38 @@ func onMessage(sess *dg.Session, msg *dg.MessageCreate) {
 	}
 
 	// Creating parser
+	inv := commands.NewInvocation(sess, msg, routeService, start)
 	parser, err := kong.New(&commands.Root{},
 		kong.Exit(func(int) {}),
 		kong.Help(createHelpPrinter(sess, msg)),
+		kong.TypeMapper(reflect.TypeOf(commands.RouteSelectors{}), kong.MapperFunc(commands.RouteSelectorsMapper)),
 
 		// Binding all the things
+		kong.Bind(inv),
 		kong.Bind(sess),
 		kong.Bind(msg),
 		kong.Bind(routeService),
+		kong.Bind(auditRepo),
+		kong.Bind(boardUpdater),
+		kong.Bind(memberCache),
 		kong.Bind(start),
 	)
 	if err != nil {
 		panic(err)
 	}
 
-	// Trying to find a command to execute with the message content
-	cmd, err := parser.Parse(parts)
+	// Parsing and executing the command through the middleware pipeline. The channel is
+	// locked before parsing so the command is validated against the latest map
+	err = commands.Chain(commands.Run,
+		commands.Serialize(channelLocks),
+		commands.Parse(parser, parts),
+		commands.TranslateErrors(),
+		commands.Log(),
+		commands.RateLimit(rateLimiter),
+		commands.Authorize(),
+		commands.Audit(auditRepo),
+		commands.CheckLock(),
+	)(inv)
 	if err != nil {
 		if embed := errorToEmbed(err); embed != nil {
 			sess.ChannelMessageSendEmbed(msg.ChannelID, embed)
@@ -134,16 +187,23 @@ func onMessage(sess *dg.Session, msg *dg.MessageCreate) {
 
 		return
 	}
+}
 
-	// Executing the command
-	err = cmd.Run()
-	if err != nil {
-		if embed := errorToEmbed(err); embed != nil {
-			sess.ChannelMessageSendEmbed(msg.ChannelID, embed)
-		} else {
-			fmt.Println(err)
+// onReactionAdd handles reactions added to messages
+func onReactionAdd(sess *dg.Session, evt *dg.MessageReactionAdd) {
+	defer func() {
+		if perr := recover(); perr != nil {
+			fmt.Println("Recovered from panic: ", perr)
 		}
+	}()
 
+	if evt.UserID == sess.State.User.ID {
 		return
 	}
+
+	// Resolving link requests one at a time with the commands in the channel
+	unlock := channelLocks.Lock(evt.ChannelID)
+	defer unlock()
+
+	commands.ResolveRequestReaction(sess, routeService, auditRepo, boardUpdater, evt)
 }
@@ -0,0 +1,66 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// AlertState remembers what officers have already been alerted about for a channel so the
+// same gap is not announced more than once
+type AlertState struct {
+	ChannelID string    `json:"channel_id"`
+	Paths     []string  `json:"paths,omitempty"`
+	Deadline  time.Time `json:"deadline,omitempty"`
+}
+
+// HasPath returns true if officers were alerted that the path has nobody on it
+func (s AlertState) HasPath(key string) bool {
+	for _, p := range s.Paths {
+		if p == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetAlertState returns what officers have been alerted about for the channel. An empty
+// state is returned if no alerts have been sent
+func (repo *Repository) GetAlertState(ctx context.Context, channelID string) (AlertState, error) {
+	s := AlertState{ChannelID: channelID}
+	err := repo.InTransaction(ctx, false, func(_ context.Context, tx *bolt.Tx) error {
+		buk := tx.Bucket([]byte("alerts"))
+		if buk == nil {
+			return nil
+		}
+
+		data := buk.Get([]byte(channelID))
+		if data == nil {
+			return nil
+		}
+
+		return json.Unmarshal(data, &s)
+	})
+
+	return s, err
+}
+
+// SetAlertState persists what officers have been alerted about for the channel
+func (repo *Repository) SetAlertState(ctx context.Context, s AlertState) error {
+	return repo.InTransaction(ctx, true, func(_ context.Context, tx *bolt.Tx) error {
+		buk, err := tx.CreateBucketIfNotExists([]byte("alerts"))
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+
+		return buk.Put([]byte(s.ChannelID), data)
+	})
+}
@@ -0,0 +1,156 @@
+package route
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// Archive is a snapshot of a channel's map and routes from a past raid
+type Archive struct {
+	ID        uint64    `json:"id"`
+	ChannelID string    `json:"channel_id"`
+	GuildID   string    `json:"guild_id,omitempty"`
+	Time      time.Time `json:"time"`
+	Reason    string    `json:"reason"`
+	ActorID   string    `json:"actor_id,omitempty"`
+	Map       Map       `json:"map"`
+	Routes    []Route   `json:"routes"`
+}
+
+// InsertArchive persists the archive and sets its ID. IDs are counted separately for
+// each channel starting at 1
+func (repo *Repository) InsertArchive(ctx context.Context, a *Archive) error {
+	return repo.InTransaction(ctx, true, func(_ context.Context, tx *bolt.Tx) error {
+		buk, err := tx.CreateBucketIfNotExists([]byte("archives"))
+		if err != nil {
+			return err
+		}
+
+		channel, err := buk.CreateBucketIfNotExists([]byte(a.ChannelID))
+		if err != nil {
+			return err
+		}
+
+		a.ID, err = channel.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+
+		return channel.Put(archiveKey(a.ID), data)
+	})
+}
+
+// GetArchives returns up to limit of the latest archives for the channel with the newest first
+func (repo *Repository) GetArchives(ctx context.Context, channelID string, limit int) ([]Archive, error) {
+	archives := []Archive{}
+	err := repo.InTransaction(ctx, false, func(_ context.Context, tx *bolt.Tx) error {
+		buk := tx.Bucket([]byte("archives"))
+		if buk == nil {
+			return nil
+		}
+
+		channel := buk.Bucket([]byte(channelID))
+		if channel == nil {
+			return nil
+		}
+
+		c := channel.Cursor()
+		for k, v := c.Last(); k != nil && len(archives) < limit; k, v = c.Prev() {
+			a := Archive{}
+			if err := json.Unmarshal(v, &a); err != nil {
+				return err
+			}
+
+			archives = append(archives, a)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return archives, nil
+}
+
+// GetArchivesSince returns the archives for every channel that were made at or after the time
+func (repo *Repository) GetArchivesSince(ctx context.Context, since time.Time) ([]Archive, error) {
+	archives := []Archive{}
+	err := repo.InTransaction(ctx, false, func(_ context.Context, tx *bolt.Tx) error {
+		buk := tx.Bucket([]byte("archives"))
+		if buk == nil {
+			return nil
+		}
+
+		return buk.ForEach(func(k, _ []byte) error {
+			channel := buk.Bucket(k)
+			if channel == nil {
+				return nil
+			}
+
+			// Archives are stored in the order they were made so the newest are last
+			c := channel.Cursor()
+			for k, v := c.Last(); k != nil; k, v = c.Prev() {
+				a := Archive{}
+				if err := json.Unmarshal(v, &a); err != nil {
+					return err
+				}
+				if a.Time.Before(since) {
+					break
+				}
+
+				archives = append(archives, a)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return archives, nil
+}
+
+// GetArchive returns the archive for the channel with the ID. Returns sql.ErrNoRows if the
+// archive could not be found
+func (repo *Repository) GetArchive(ctx context.Context, channelID string, id uint64) (Archive, error) {
+	a := Archive{}
+	err := repo.InTransaction(ctx, false, func(_ context.Context, tx *bolt.Tx) error {
+		buk := tx.Bucket([]byte("archives"))
+		if buk == nil {
+			return sql.ErrNoRows
+		}
+
+		channel := buk.Bucket([]byte(channelID))
+		if channel == nil {
+			return sql.ErrNoRows
+		}
+
+		data := channel.Get(archiveKey(id))
+		if data == nil {
+			return sql.ErrNoRows
+		}
+
+		return json.Unmarshal(data, &a)
+	})
+
+	return a, err
+}
+
+// archiveKey creates the key for an archive so archives are sorted by the order they were added
+func archiveKey(id uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, id)
+	return b
+}
@@ -0,0 +1,65 @@
+package route
+
+// Coverage describes how much of a map has users linked to it
+type Coverage struct {
+	Paths      int
+	Slots      int
+	Filled     int
+	Covered    int
+	Unassigned int
+}
+
+// ComputeCoverage counts the paths on the map that are full or have nobody linked to them.
+// Only users on the path count towards it being filled
+func ComputeCoverage(m Map, idx map[string][]Route) Coverage {
+	c := Coverage{}
+	capacity := m.PathCapacity()
+	for section := 1; section <= int(m.Sections); section++ {
+		for _, p := range m.Paths(section) {
+			primary, _, _ := SplitRoutes(idx[RouteKey(section, p)])
+			c.Paths++
+			c.Slots += capacity
+
+			switch n := len(primary); {
+			case n == 0:
+				c.Unassigned++
+			case n >= capacity:
+				c.Filled += capacity
+				c.Covered++
+			default:
+				c.Filled += n
+			}
+		}
+	}
+
+	return c
+}
+
+// Add combines the coverage of two maps
+func (c Coverage) Add(o Coverage) Coverage {
+	return Coverage{
+		Paths:      c.Paths + o.Paths,
+		Slots:      c.Slots + o.Slots,
+		Filled:     c.Filled + o.Filled,
+		Covered:    c.Covered + o.Covered,
+		Unassigned: c.Unassigned + o.Unassigned,
+	}
+}
+
+// FillPercent returns the percentage of the places on paths that have users linked to them
+func (c Coverage) FillPercent() int {
+	if c.Slots == 0 {
+		return 0
+	}
+
+	return c.Filled * 100 / c.Slots
+}
+
+// CompletePercent returns the percentage of paths that are full
+func (c Coverage) CompletePercent() int {
+	if c.Paths == 0 {
+		return 0
+	}
+
+	return c.Covered * 100 / c.Paths
+}
@@ -0,0 +1,170 @@
+package route
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"unicode/utf8"
+)
+
+// Sizes used when drawing the routes as an image
+const (
+	glyphWidth    = 5
+	glyphHeight   = 7
+	glyphScale    = 2
+	charAdvance   = (glyphWidth + 1) * glyphScale
+	lineHeight    = (glyphHeight + 3) * glyphScale
+	cellPadding   = 8
+	gridLine      = 2
+	maxNameLength = 16
+)
+
+// Colours used when drawing the routes as an image
+var (
+	backgroundColour  = color.RGBA{0x20, 0x22, 0x25, 0xFF}
+	headerColour      = color.RGBA{0x99, 0xB2, 0xDD, 0xFF}
+	unavailableColour = color.RGBA{0x40, 0x44, 0x4B, 0xFF}
+	emptyColour       = color.RGBA{0xED, 0x42, 0x45, 0xFF}
+	partialColour     = color.RGBA{0xFA, 0xA6, 0x1A, 0xFF}
+	fullColour        = color.RGBA{0x3B, 0xA5, 0x5D, 0xFF}
+	overColour        = color.RGBA{0x58, 0x65, 0xF2, 0xFF}
+	textColour        = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	headerTextColour  = color.RGBA{0x20, 0x22, 0x25, 0xFF}
+)
+
+// ComposeImage draws the routes as a table with a column for each section and a row for
+// each path. Cells show the names of the users linked to the path and are coloured by how
+// full the path is. Users on standby and requests waiting for approval are listed last with
+// a plus or question mark in front of their name and users a path is reserved for have R: in
+// front of their name. names maps user IDs to the name shown for the user and the ID is
+// shown for users that are not in names
+func (s *Service) ComposeImage(m Map, idx map[string][]Route, names map[string]string) image.Image {
+	capacity := m.PathCapacity()
+
+	// Finding the number of rows and the number of names in the fullest cell
+	rows, lines := 0, capacity
+	for i := 1; i <= int(m.Sections); i++ {
+		paths := m.Paths(i)
+		if len(paths) > rows {
+			rows = len(paths)
+		}
+
+		for _, p := range paths {
+			if n := len(idx[RouteKey(i, p)]); n > lines {
+				lines = n
+			}
+		}
+	}
+
+	labelWidth := charAdvance + 2*cellPadding
+	colWidth := maxNameLength*charAdvance + 2*cellPadding
+	headerHeight := lineHeight + 2*cellPadding
+	rowHeight := (lines+1)*lineHeight + 2*cellPadding
+	width := labelWidth + int(m.Sections)*(colWidth+gridLine) + gridLine
+	height := headerHeight + rows*(rowHeight+gridLine) + gridLine
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fillRect(img, img.Bounds(), backgroundColour)
+
+	// Drawing the path labels
+	for row := 0; row < rows; row++ {
+		y := headerHeight + gridLine + row*(rowHeight+gridLine)
+		drawText(img, cellPadding, y+cellPadding, string(rune('A'+row)), textColour)
+	}
+
+	for i := 1; i <= int(m.Sections); i++ {
+		x := labelWidth + gridLine + (i-1)*(colWidth+gridLine)
+		paths := m.Paths(i)
+
+		// Drawing the section header
+		fillRect(img, image.Rect(x, gridLine, x+colWidth, headerHeight), headerColour)
+		drawText(img, x+cellPadding, gridLine+cellPadding, fmt.Sprintf("Section %d", i), headerTextColour)
+
+		for row := 0; row < rows; row++ {
+			y := headerHeight + gridLine + row*(rowHeight+gridLine)
+			cell := image.Rect(x, y, x+colWidth, y+rowHeight)
+
+			// Path does not exist in this section
+			if row >= len(paths) {
+				fillRect(img, cell, unavailableColour)
+				continue
+			}
+
+			primary, standby, pending := SplitRoutes(idx[RouteKey(i, paths[row])])
+			fillRect(img, cell, cellColour(len(primary), capacity))
+			drawText(img, x+cellPadding, y+cellPadding, fmt.Sprintf("%d/%d", len(primary), capacity), textColour)
+			for j, r := range append(append(primary, standby...), pending...) {
+				name, ok := names[r.UserID]
+				if !ok {
+					name = r.UserID
+				}
+				if r.Pending {
+					name = "?" + name
+				} else if r.Standby {
+					name = "+" + name
+				} else if r.Reserved {
+					name = "R:" + name
+				}
+
+				drawText(img, x+cellPadding, y+cellPadding+(j+1)*lineHeight, shorten(name, maxNameLength), textColour)
+			}
+		}
+	}
+
+	return img
+}
+
+// cellColour returns the colour for a cell with count users linked to it
+func cellColour(count, capacity int) color.Color {
+	switch {
+	case count == 0:
+		return emptyColour
+	case count < capacity:
+		return partialColour
+	case count == capacity:
+		return fullColour
+	default:
+		return overColour
+	}
+}
+
+// fillRect fills the rectangle on the image with the colour
+func fillRect(img draw.Image, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, &image.Uniform{C: c}, image.Point{}, draw.Src)
+}
+
+// drawText draws the text on the image with its top left corner at x and y. Characters
+// that are not in the font are drawn as a question mark
+func drawText(img draw.Image, x, y int, text string, c color.Color) {
+	i := 0
+	for _, r := range strings.ToUpper(text) {
+		g, ok := glyphs[r]
+		if !ok {
+			g = glyphs['?']
+		}
+
+		ox := x + i*charAdvance
+		for row, line := range g {
+			for col, px := range line {
+				if px != '#' {
+					continue
+				}
+
+				px := image.Rect(0, 0, glyphScale, glyphScale).Add(image.Pt(ox+col*glyphScale, y+row*glyphScale))
+				fillRect(img, px, c)
+			}
+		}
+		i++
+	}
+}
+
+// shorten cuts the text down to limit characters
+func shorten(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+
+	return string([]rune(text)[:limit-1]) + "."
+}
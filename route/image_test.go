@@ -0,0 +1,132 @@
+package route
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update the golden images in testdata")
+
+func TestComposeImageGolden(t *testing.T) {
+	linked := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		m      Map
+		routes []Route
+		names  map[string]string
+	}{
+		{
+			name: "empty",
+			m:    Map{Sections: 2, MaxPaths: []string{"C", "B"}},
+		},
+		{
+			name: "linked",
+			m:    Map{Sections: 3, MaxPaths: []string{"B", "C", "A"}, Capacity: 2},
+			routes: []Route{
+				{UserID: "1", Section: 1, Path: "A", CreatedAt: linked},
+				{UserID: "2", Section: 1, Path: "A", CreatedAt: linked.Add(time.Minute)},
+				{UserID: "3", Section: 2, Path: "C", CreatedAt: linked},
+				{UserID: "4", Section: 3, Path: "A", CreatedAt: linked},
+				{UserID: "5", Section: 3, Path: "A", CreatedAt: linked.Add(time.Minute)},
+				{UserID: "6", Section: 3, Path: "A", CreatedAt: linked.Add(2 * time.Minute)},
+			},
+			names: map[string]string{"1": "Fury", "2": "Hill", "3": "Coulson", "4": "Romanoff", "5": "Barton", "6": "Rogers"},
+		},
+		{
+			name: "statuses",
+			m:    Map{Sections: 2, MaxPaths: []string{"B", "B"}},
+			routes: []Route{
+				{UserID: "1", Section: 1, Path: "A", CreatedAt: linked},
+				{UserID: "2", Section: 1, Path: "A", CreatedAt: linked.Add(time.Minute), Standby: true},
+				{UserID: "3", Section: 1, Path: "A", CreatedAt: linked.Add(2 * time.Minute), Pending: true},
+				{UserID: "4", Section: 2, Path: "B", CreatedAt: linked, Reserved: true, ExpiresAt: linked.Add(time.Hour)},
+			},
+			names: map[string]string{"1": "Fury", "2": "Hill", "3": "Coulson", "4": "Romanoff"},
+		},
+		{
+			name: "names",
+			m:    Map{Sections: 1, MaxPaths: []string{"B"}},
+			routes: []Route{
+				{UserID: "1", Section: 1, Path: "A", CreatedAt: linked},
+				{UserID: "123456789012345678", Section: 1, Path: "B", CreatedAt: linked},
+			},
+			names: map[string]string{"1": "A name that is far too long to fit"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := (&Service{}).ComposeImage(tt.m, IndexRoutes(tt.routes), tt.names)
+
+			path := filepath.Join("testdata", tt.name+".png")
+			if *update {
+				writeGolden(t, path, got)
+			}
+
+			want := readGolden(t, path)
+			if !got.Bounds().Eq(want.Bounds()) {
+				t.Fatalf("got an image of %v, want %v from %s", got.Bounds(), want.Bounds(), path)
+			}
+
+			// Comparing pixels instead of the encoded files as PNG compression can change
+			// between Go versions
+			b := got.Bounds()
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					if !sameColour(got.At(x, y), want.At(x, y)) {
+						t.Fatalf("pixel (%d, %d) is %v, want %v from %s (run go test ./route -run ComposeImage -update if the change is intended)", x, y, got.At(x, y), want.At(x, y), path)
+					}
+				}
+			}
+		})
+	}
+}
+
+// writeGolden encodes the image as a PNG to the path
+func writeGolden(t *testing.T, path string, img image.Image) {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatalf("encoding image: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("creating %s: %v", filepath.Dir(path), err)
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("writing %s: %v", path, err)
+	}
+}
+
+// readGolden decodes the PNG at the path
+func readGolden(t *testing.T, path string) image.Image {
+	t.Helper()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading %s: %v (run go test ./route -run ComposeImage -update)", path, err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decoding %s: %v", path, err)
+	}
+
+	return img
+}
+
+// sameColour returns true if the colours are the same once converted to the same colour model
+func sameColour(a, b color.Color) bool {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	return ar == br && ag == bg && ab == bb && aa == ba
+}
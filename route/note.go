@@ -0,0 +1,56 @@
+package route
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits for notes on routes
+const (
+	MaxNoteLength     = 100
+	compactNoteLength = 40
+)
+
+// SanitizeNote makes a note safe to show on a board. The note is put on a single line and
+// characters that could mention someone or break the board's formatting are removed
+func SanitizeNote(note string) string {
+	note = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r) || unicode.Is(unicode.Cf, r):
+			return ' '
+		case strings.ContainsRune("<>@`", r):
+			return -1
+		}
+
+		return r
+	}, note)
+
+	return strings.Join(strings.Fields(note), " ")
+}
+
+// sanitizeName makes a display name safe to show in a code block. The name is put on a single
+// line and backticks that could end the code block are removed
+func sanitizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r) || unicode.Is(unicode.Cf, r):
+			return ' '
+		case r == '`':
+			return -1
+		}
+
+		return r
+	}, name)
+
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// compactNote shortens the note so it takes up little room on a board
+func compactNote(note string) string {
+	if utf8.RuneCountInString(note) <= compactNoteLength {
+		return note
+	}
+
+	return string([]rune(note)[:compactNoteLength-1]) + "…"
+}
@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/duke605/NickFury/datastore"
@@ -13,18 +15,47 @@ import (
 
 // Route ...
 type Route struct {
-	ID        string `json:"id"`
-	UserID    string `json:"user_id"`
-	ChannelID string `json:"channel_id"`
-	Section   int    `json:"section"`
-	Path      string `json:"path"`
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	ChannelID string    `json:"channel_id"`
+	Section   int       `json:"section"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Note      string    `json:"note,omitempty"`
+	Standby   bool      `json:"standby,omitempty"`
+	Reserved  bool      `json:"reserved,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	Pending   bool      `json:"pending,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
 }
 
+// Formats a route board can be shown in
+const (
+	FormatEmbed = "embed"
+	FormatText  = "text"
+)
+
+// Modes for how linked users are named on a route board
+const (
+	NameMention  = "mention"
+	NameNickname = "nickname"
+	NameUsername = "username"
+)
+
 // Map ...
 type Map struct {
-	ID       string   `json:"id"`
-	Sections byte     `json:"sections"`
-	MaxPaths []string `json:"max_paths"`
+	ID             string        `json:"id"`
+	Sections       byte          `json:"sections"`
+	MaxPaths       []string      `json:"max_paths"`
+	Locked         bool          `json:"locked"`
+	Capacity       int           `json:"capacity,omitempty"`
+	Format         string        `json:"format,omitempty"`
+	NameMode       string        `json:"name_mode,omitempty"`
+	Approval       bool          `json:"approval,omitempty"`
+	AlertChannelID string        `json:"alert_channel_id,omitempty"`
+	AlertBefore    time.Duration `json:"alert_before,omitempty"`
+	EndsAt         time.Time     `json:"ends_at,omitempty"`
 }
 
 // Paths returns the valid paths for the provided section
@@ -33,6 +64,15 @@ func (m Map) Paths(section int) []string {
 	return strings.Split("ABCDEFGHIJKLMNOPQRSTUVWXYZ"[:limit], "")
 }
 
+// PathCapacity returns the number of users that are expected to be linked to each path
+func (m Map) PathCapacity() int {
+	if m.Capacity < 1 {
+		return 1
+	}
+
+	return m.Capacity
+}
+
 // IsValidPath returns true if the path is valid for the section
 func (m Map) IsValidPath(section int, p string) bool {
 	paths := m.Paths(section)
@@ -49,6 +89,67 @@ func (r Route) GetID() []byte {
 	return []byte(r.ID)
 }
 
+// LastChanged returns when the route was last changed. The time is zero for routes that
+// were linked before times were recorded
+func (r Route) LastChanged() time.Time {
+	if r.UpdatedAt.IsZero() {
+		return r.CreatedAt
+	}
+
+	return r.UpdatedAt
+}
+
+// IsExpired returns true if the route is a reservation that was not claimed in time
+func (r Route) IsExpired(now time.Time) bool {
+	return r.Reserved && !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
+
+// SortByClaim sorts the routes by when they were first linked. Routes without a time are
+// treated as the oldest
+func SortByClaim(routes []Route) {
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].CreatedAt.Before(routes[j].CreatedAt)
+	})
+}
+
+// IndexRoutes groups the routes by section and path for quick lookup. The routes for each
+// path are in the order they were linked
+func IndexRoutes(routes []Route) map[string][]Route {
+	sorted := append([]Route{}, routes...)
+	SortByClaim(sorted)
+
+	idx := map[string][]Route{}
+	for _, r := range sorted {
+		key := RouteKey(r.Section, r.Path)
+		idx[key] = append(idx[key], r)
+	}
+
+	return idx
+}
+
+// SplitRoutes splits the routes into the routes of users that are on the path, users on
+// standby for it and requests for the path that have not been approved. The order of the
+// routes is kept
+func SplitRoutes(routes []Route) (primary, standby, pending []Route) {
+	for _, r := range routes {
+		switch {
+		case r.Pending:
+			pending = append(pending, r)
+		case r.Standby:
+			standby = append(standby, r)
+		default:
+			primary = append(primary, r)
+		}
+	}
+
+	return primary, standby, pending
+}
+
+// RouteKey returns the key the routes for a section and path are indexed by
+func RouteKey(section int, path string) string {
+	return fmt.Sprintf("%d:%s", section, path)
+}
+
 // Repository handles the communication between the application and
 // persistant storage
 type Repository struct {
@@ -94,6 +195,64 @@ func (repo *Repository) GetRoutesInChannel(ctx context.Context, channelID string
 	return routes, nil
 }
 
+// GetRoutesForUser gets all the routes the user is linked to in every channel
+func (repo *Repository) GetRoutesForUser(ctx context.Context, userID string) ([]Route, error) {
+	routes := []Route{}
+	err := repo.InTransaction(ctx, false, func(_ context.Context, tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("routes"))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			r := Route{}
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+
+			if r.UserID == userID {
+				routes = append(routes, r)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return routes, nil
+}
+
+// GetExpiredReservations returns the reservations in every channel that have expired
+func (repo *Repository) GetExpiredReservations(ctx context.Context, now time.Time) ([]Route, error) {
+	routes := []Route{}
+	err := repo.InTransaction(ctx, false, func(_ context.Context, tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("routes"))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			r := Route{}
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+
+			if r.IsExpired(now) {
+				routes = append(routes, r)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return routes, nil
+}
+
 // DeleteAllRoutesForChannel deletes all assined routes for a channel
 func (repo *Repository) DeleteAllRoutesForChannel(ctx context.Context, channelID string) error {
 	return repo.InTransaction(ctx, true, func(ctx context.Context, tx *bolt.Tx) error {
@@ -164,6 +323,32 @@ func (repo *Repository) GetMapForChannel(ctx context.Context, channelID string)
 	return m, err
 }
 
+// GetMaps returns the maps for every channel
+func (repo *Repository) GetMaps(ctx context.Context) ([]Map, error) {
+	maps := []Map{}
+	err := repo.InTransaction(ctx, false, func(_ context.Context, tx *bolt.Tx) error {
+		buk := tx.Bucket([]byte("maps"))
+		if buk == nil {
+			return nil
+		}
+
+		return buk.ForEach(func(k, v []byte) error {
+			m := Map{}
+			if err := json.Unmarshal(v, &m); err != nil {
+				return err
+			}
+
+			maps = append(maps, m)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return maps, nil
+}
+
 // InsertMap persists a map
 func (repo *Repository) InsertMap(ctx context.Context, m Map) error {
 	return repo.InTransaction(ctx, true, func(_ context.Context, tx *bolt.Tx) error {
@@ -180,3 +365,60 @@ func (repo *Repository) InsertMap(ctx context.Context, m Map) error {
 		return buk.Put([]byte(m.ID), data)
 	})
 }
+
+// DeleteMap deletes the map for the channel
+func (repo *Repository) DeleteMap(ctx context.Context, channelID string) error {
+	return repo.InTransaction(ctx, true, func(_ context.Context, tx *bolt.Tx) error {
+		buk := tx.Bucket([]byte("maps"))
+		if buk == nil {
+			return nil
+		}
+
+		return buk.Delete([]byte(channelID))
+	})
+}
+
+// GetBoardMessageIDs returns the IDs of the messages the route board for the channel is
+// displayed in. Returns sql.ErrNoRows if the channel does not have a board
+func (repo *Repository) GetBoardMessageIDs(ctx context.Context, channelID string) ([]string, error) {
+	ids := []string{}
+	err := repo.InTransaction(ctx, false, func(_ context.Context, tx *bolt.Tx) error {
+		buk := tx.Bucket([]byte("boards"))
+		if buk == nil {
+			return sql.ErrNoRows
+		}
+
+		data := buk.Get([]byte(channelID))
+		if data == nil {
+			return sql.ErrNoRows
+		}
+
+		// Boards used to only be a single message and were stored as the raw ID
+		if data[0] != '[' {
+			ids = append(ids, string(data))
+			return nil
+		}
+
+		return json.Unmarshal(data, &ids)
+	})
+
+	return ids, err
+}
+
+// SetBoardMessageIDs persists the IDs of the messages the route board for the channel
+// is displayed in
+func (repo *Repository) SetBoardMessageIDs(ctx context.Context, channelID string, messageIDs []string) error {
+	return repo.InTransaction(ctx, true, func(_ context.Context, tx *bolt.Tx) error {
+		buk, err := tx.CreateBucketIfNotExists([]byte("boards"))
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(messageIDs)
+		if err != nil {
+			return err
+		}
+
+		return buk.Put([]byte(channelID), data)
+	})
+}
@@ -3,6 +3,7 @@ package route
 import (
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
 )
@@ -19,23 +20,78 @@ func NewService(repo *Repository) *Service {
 	}
 }
 
-// ComposeEmbed creates an embed showing who is linked to what route in the channel
-func (s *Service) ComposeEmbed(m Map, idx map[string][]string) *discordgo.MessageEmbed {
+// Limits discord puts on embeds
+const (
+	maxEmbedFields     = 25
+	maxEmbedFieldValue = 1024
+	maxEmbedSize       = 6000
+)
+
+// lockedFooter is shown at the bottom of the board when the routes are locked
+const lockedFooter = "\U0001F512 Locked"
+
+// ComposeEmbeds creates the embeds showing who is linked to what route in the channel. Sections
+// that are too long for one field are split across multiple fields and the fields are split
+// across multiple embeds so every embed is within discord's limits. names maps user IDs to the
+// name shown for the user and users that are not in names are mentioned
+func (s *Service) ComposeEmbeds(m Map, idx map[string][]Route, names map[string]string) []*discordgo.MessageEmbed {
 
 	// Creating fields
-	fields := make([]*discordgo.MessageEmbedField, m.Sections)
+	fields := []*discordgo.MessageEmbedField{}
 	for i := 0; i < int(m.Sections); i++ {
 		suffix := ""
 		if i != int(m.Sections-1) {
-			suffix = string(0x200B)
+			suffix = "\n\u200B"
 		}
 
-		fields[i] = &discordgo.MessageEmbedField{
-			Name:  fmt.Sprintf("__Section %d__", i+1),
-			Value: s.ComposeSectionText(m, idx, i+1) + "\n" + suffix,
+		name := fmt.Sprintf("__Section %d__", i+1)
+		chunks := splitText(s.ComposeSectionText(m, idx, names, i+1), maxEmbedFieldValue-len([]rune(suffix)))
+		for j, c := range chunks {
+			f := &discordgo.MessageEmbedField{Name: name, Value: c}
+			if j > 0 {
+				f.Name += " (cont.)"
+			}
+			if j == len(chunks)-1 {
+				f.Value += suffix
+			}
+
+			fields = append(fields, f)
 		}
 	}
 
+	// Leaving room in every embed for the footer since any of them could end up being the last
+	var footer *discordgo.MessageEmbedFooter
+	reserved := 0
+	if m.Locked {
+		footer = &discordgo.MessageEmbedFooter{Text: lockedFooter}
+		reserved = utf8.RuneCountInString(lockedFooter)
+	}
+
+	// Splitting the fields across embeds
+	embeds := []*discordgo.MessageEmbed{newRoutesEmbed()}
+	size := EmbedSize(embeds[0]) + reserved
+	for _, f := range fields {
+		embed := embeds[len(embeds)-1]
+		fsize := utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+		if len(embed.Fields) == maxEmbedFields || size+fsize > maxEmbedSize {
+			embed = newRoutesEmbed()
+			embed.Thumbnail = nil
+			embeds = append(embeds, embed)
+			size = EmbedSize(embed) + reserved
+		}
+
+		embed.Fields = append(embed.Fields, f)
+		size += fsize
+	}
+
+	// Indicating that the routes can not be changed by members
+	embeds[len(embeds)-1].Footer = footer
+
+	return embeds
+}
+
+// newRoutesEmbed creates an empty embed for routes
+func newRoutesEmbed() *discordgo.MessageEmbed {
 	return &discordgo.MessageEmbed{
 		Author: &discordgo.MessageEmbedAuthor{
 			Name:    "Routes",
@@ -45,28 +101,42 @@ func (s *Service) ComposeEmbed(m Map, idx map[string][]string) *discordgo.Messag
 			URL:    "https://i.imgur.com/KHHO0DY.png",
 			Height: 1000,
 		},
-		Color:  0x99B2DD,
-		Fields: fields,
+		Color: 0x99B2DD,
 	}
 }
 
-// ComposeSectionText creates a string for an embed field showing who is linked to a section
-func (s *Service) ComposeSectionText(m Map, idx map[string][]string, section int) string {
+// EmbedSize returns the number of characters in the embed that count towards
+// discord's size limit
+func EmbedSize(e *discordgo.MessageEmbed) int {
+	size := utf8.RuneCountInString(e.Title) + utf8.RuneCountInString(e.Description)
+	if e.Author != nil {
+		size += utf8.RuneCountInString(e.Author.Name)
+	}
+	if e.Footer != nil {
+		size += utf8.RuneCountInString(e.Footer.Text)
+	}
+	for _, f := range e.Fields {
+		size += utf8.RuneCountInString(f.Name) + utf8.RuneCountInString(f.Value)
+	}
+
+	return size
+}
+
+// ComposeSectionText creates a string for an embed field showing who is linked to a section.
+// Users that are not in names are mentioned
+func (s *Service) ComposeSectionText(m Map, idx map[string][]Route, names map[string]string, section int) string {
 	paths := m.Paths(section)
 
 	str := ""
 	for i := 0; i < len(paths); i++ {
-		list := ""
-
 		// Getting persons assigned to current route
-		key := fmt.Sprintf("%d:%s", section, paths[i])
-		if ids, ok := idx[key]; ok {
-			mentions := make([]string, len(ids))
-			for i, id := range ids {
-				mentions[i] = fmt.Sprintf("<@!%s>", id)
-			}
-
-			list = strings.Join(mentions, "/")
+		primary, standby, pending := SplitRoutes(idx[RouteKey(section, paths[i])])
+		list := mentionList(primary, names)
+		if len(standby) > 0 {
+			list = strings.TrimLeft(list+" | *standby:* "+mentionList(standby, names), " |")
+		}
+		if len(pending) > 0 {
+			list = strings.TrimLeft(list+" | *pending:* "+mentionList(pending, names), " |")
 		}
 
 		str += strings.Trim(fmt.Sprintf("**%s:** %s", paths[i], list), " ")
@@ -76,3 +146,87 @@ func (s *Service) ComposeSectionText(m Map, idx map[string][]string, section int
 	str = strings.Trim(str, " \n")
 	return str
 }
+
+// mentionList creates a string with the users of the routes separated by slashes. Users that
+// are not in names are mentioned
+func mentionList(routes []Route, names map[string]string) string {
+	mentions := make([]string, len(routes))
+	for i, r := range routes {
+		mentions[i] = fmt.Sprintf("<@!%s>", r.UserID)
+		if name, ok := names[r.UserID]; ok {
+			mentions[i] = EscapeMarkdown(name)
+		}
+		if r.Reserved {
+			mentions[i] += fmt.Sprintf(" *(reserved until <t:%d:t>)*", r.ExpiresAt.Unix())
+		}
+		if r.Note != "" {
+			mentions[i] += fmt.Sprintf(" *(%s)*", EscapeMarkdown(compactNote(r.Note)))
+		}
+	}
+
+	return strings.Join(mentions, "/")
+}
+
+// EscapeMarkdown escapes the characters in the text that discord would treat as formatting
+func EscapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"~", `\~`,
+	"`", "\\`",
+	"|", `\|`,
+	">", `\>`,
+)
+
+// splitText splits the text into chunks that are no longer than limit characters. Text is
+// split on new lines first, then on the separators between mentions and as a last resort
+// anywhere in the text
+func splitText(text string, limit int) []string {
+	chunks := []string{}
+	current := ""
+
+	for _, line := range strings.Split(text, "\n") {
+		for _, part := range splitLine(line, limit) {
+			if current == "" {
+				current = part
+			} else if utf8.RuneCountInString(current)+1+utf8.RuneCountInString(part) <= limit {
+				current += "\n" + part
+			} else {
+				chunks = append(chunks, current)
+				current = part
+			}
+		}
+	}
+
+	return append(chunks, current)
+}
+
+// splitLine splits a line that is too long into parts that are no longer than limit
+// characters
+func splitLine(line string, limit int) []string {
+	if utf8.RuneCountInString(line) <= limit {
+		return []string{line}
+	}
+
+	parts := []string{}
+	current := ""
+	for i, mention := range strings.SplitAfter(line, "/") {
+		if i > 0 && utf8.RuneCountInString(current)+utf8.RuneCountInString(mention) > limit {
+			parts = append(parts, current)
+			current = ""
+		}
+		current += mention
+
+		// Cutting text that can not be split any other way
+		for r := []rune(current); len(r) > limit; r = []rune(current) {
+			parts = append(parts, string(r[:limit]))
+			current = string(r[limit:])
+		}
+	}
+
+	return append(parts, current)
+}
@@ -0,0 +1,98 @@
+package route
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// boardFixture creates a map with a section for each name length. Each section has a single
+// path with a user named with that many characters linked to it or nobody when the length is 0
+func boardFixture(nameLengths []int, locked bool) (Map, map[string][]Route, map[string]string) {
+	m := Map{Sections: byte(len(nameLengths)), Locked: locked}
+	routes := []Route{}
+	names := map[string]string{}
+	for i, n := range nameLengths {
+		m.MaxPaths = append(m.MaxPaths, "A")
+		if n == 0 {
+			continue
+		}
+
+		userID := fmt.Sprintf("user%d", i+1)
+		routes = append(routes, Route{UserID: userID, Section: i + 1, Path: "A"})
+		names[userID] = strings.Repeat("x", n)
+	}
+
+	return m, IndexRoutes(routes), names
+}
+
+func TestComposeEmbedsBoundaries(t *testing.T) {
+
+	// Every section's field is "__Section n__" (13 characters) with a value of "**A:** " and
+	// the name followed by "\n\u200B" for all but the last section. The embed's author is
+	// "Routes" (6 characters) so six sections with these names add up to exactly 6000
+	fullEmbed := []int{1015, 1015, 1015, 1015, 1015, 789}
+	overFullEmbed := []int{1015, 1015, 1015, 1015, 1015, 790}
+	fullLockedEmbed := []int{1015, 1015, 1015, 1015, 1015, 781}
+
+	tests := []struct {
+		name        string
+		nameLengths []int
+		locked      bool
+		wantFields  []int
+	}{
+		{name: "25 fields", nameLengths: make([]int, 25), wantFields: []int{25}},
+		{name: "26 fields", nameLengths: make([]int, 26), wantFields: []int{25, 1}},
+		{name: "section text of 1024 runes", nameLengths: []int{1017}, wantFields: []int{1}},
+		{name: "section text of 1025 runes", nameLengths: []int{1018}, wantFields: []int{2}},
+		{name: "embed size of 6000", nameLengths: fullEmbed, wantFields: []int{6}},
+		{name: "embed size of 6001", nameLengths: overFullEmbed, wantFields: []int{5, 1}},
+		{name: "locked embed size of 6000", nameLengths: fullLockedEmbed, locked: true, wantFields: []int{6}},
+		{name: "locked embed size of 6008", nameLengths: fullEmbed, locked: true, wantFields: []int{5, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, idx, names := boardFixture(tt.nameLengths, tt.locked)
+			embeds := (&Service{}).ComposeEmbeds(m, idx, names)
+
+			if len(embeds) != len(tt.wantFields) {
+				t.Fatalf("got %d embeds, want %d", len(embeds), len(tt.wantFields))
+			}
+
+			for i, e := range embeds {
+				if len(e.Fields) != tt.wantFields[i] {
+					t.Errorf("embed %d has %d fields, want %d", i, len(e.Fields), tt.wantFields[i])
+				}
+				if size := EmbedSize(e); size > maxEmbedSize {
+					t.Errorf("embed %d has a size of %d, want at most %d", i, size, maxEmbedSize)
+				}
+				for j, f := range e.Fields {
+					if n := utf8.RuneCountInString(f.Value); n > maxEmbedFieldValue {
+						t.Errorf("field %d of embed %d has %d runes, want at most %d", j, i, n, maxEmbedFieldValue)
+					}
+				}
+
+				last := i == len(embeds)-1
+				if hasFooter := e.Footer != nil; hasFooter != (tt.locked && last) {
+					t.Errorf("embed %d has a footer = %t, want %t", i, hasFooter, tt.locked && last)
+				}
+			}
+		})
+	}
+}
+
+func TestComposeEmbedsExactSize(t *testing.T) {
+	m, idx, names := boardFixture([]int{1015, 1015, 1015, 1015, 1015, 789}, false)
+	embeds := (&Service{}).ComposeEmbeds(m, idx, names)
+	if size := EmbedSize(embeds[0]); size != maxEmbedSize {
+		t.Fatalf("got an embed size of %d, want %d", size, maxEmbedSize)
+	}
+
+	m, idx, names = boardFixture([]int{1015, 1015, 1015, 1015, 1015, 781}, true)
+	embeds = (&Service{}).ComposeEmbeds(m, idx, names)
+	if size := EmbedSize(embeds[0]); size != maxEmbedSize {
+		t.Fatalf("got a locked embed size of %d, want %d", size, maxEmbedSize)
+	}
+}
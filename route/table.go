@@ -0,0 +1,105 @@
+package route
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits for the text table
+const (
+	maxTableMessage = 2000
+	maxTableWidth   = 60
+)
+
+// ComposeTable creates a fixed-width table showing who is linked to what route in the channel
+// with a row for each path. The table is split into code blocks that each fit in a single
+// message. names maps user IDs to the name shown for the user and the ID is shown for users
+// that are not in names
+func (s *Service) ComposeTable(m Map, idx map[string][]Route, names map[string]string) []string {
+	capacity := m.PathCapacity()
+	header := "Section Path Linked Users\n" + strings.Repeat("-", maxTableWidth) + "\n"
+	prefixWidth := len("Section Path Linked ")
+
+	rows := []string{}
+	for i := 1; i <= int(m.Sections); i++ {
+		for _, p := range m.Paths(i) {
+			primary, standby, pending := SplitRoutes(idx[RouteKey(i, p)])
+			routes := append(append(primary, standby...), pending...)
+			users := make([]string, len(routes))
+			for j, r := range routes {
+				users[j] = r.UserID
+				if name := sanitizeName(names[r.UserID]); name != "" {
+					users[j] = name
+				}
+				if r.Reserved {
+					users[j] += " (reserved)"
+				}
+				if r.Note != "" {
+					users[j] += fmt.Sprintf(" (%s)", compactNote(r.Note))
+				}
+				if r.Pending {
+					users[j] += " (pending)"
+				} else if r.Standby {
+					users[j] += " (standby)"
+				}
+			}
+
+			// Wrapping the users onto more lines when they do not fit on one
+			prefix := fmt.Sprintf("%7d %4s %6s ", i, p, fmt.Sprintf("%d/%d", len(primary), capacity))
+			lines := wrapWords(users, maxTableWidth-prefixWidth)
+			for j, l := range lines {
+				if j > 0 {
+					prefix = strings.Repeat(" ", prefixWidth)
+				}
+
+				rows = append(rows, strings.TrimRight(prefix+l, " ")+"\n")
+			}
+		}
+	}
+
+	// Splitting the rows across code blocks
+	tables := []string{}
+	current := ""
+	for _, row := range rows {
+		if current != "" && utf8.RuneCountInString(wrapTable(header+current+row)) > maxTableMessage {
+			tables = append(tables, wrapTable(header+current))
+			current = ""
+		}
+
+		current += row
+	}
+
+	return append(tables, wrapTable(header+current))
+}
+
+// wrapTable puts the table in a code block
+func wrapTable(table string) string {
+	return "```\n" + table + "```"
+}
+
+// wrapWords joins the words with commas into lines that are no longer than width
+// characters. Words longer than width are shortened. Always returns at least one line
+func wrapWords(words []string, width int) []string {
+	lines := []string{}
+	current := ""
+
+	for i, w := range words {
+		w = shorten(w, width-1)
+		if i < len(words)-1 {
+			w += ","
+		}
+
+		if current != "" && utf8.RuneCountInString(current)+1+utf8.RuneCountInString(w) > width {
+			lines = append(lines, current)
+			current = ""
+		}
+
+		if current != "" {
+			current += " "
+		}
+		current += w
+	}
+
+	return append(lines, current)
+}